@@ -1,6 +1,7 @@
 package pgc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -18,22 +19,79 @@ const (
 	LimitInsert = 1000
 )
 
+// ErrStaleObject is returned by update of a struct with version field (marked with pgc:"version" tag),
+// in case the row was modified (or deleted) since the struct was fetched.
+var ErrStaleObject = errors.New("stale object: row was modified or deleted concurrently")
+
+// ErrNotFound is returned by Update or Delete of a struct, in case no row with such primary key exists.
+// Use IgnoreNotFound adapter to skip this check.
+var ErrNotFound = errors.New("row not found")
+
 // Map is a short representation of map[string]interface{}, used in adapter ops.
 type Map map[string]interface{}
 
 // TxAdapter handles basic operations with postgres under transaction.
 type TxAdapter struct {
 	*crudAdapter
+
+	// savepoint is a name of savepoint backing nested transaction, empty for top level transaction.
+	savepoint string
+	// nested is a counter of nested transactions, shared by the whole transaction tree.
+	nested *int32
+	// poolCon is a pool connection of transaction, released once the transaction is finished.
+	poolCon *pgx.Conn
+}
+
+// WithContext returns a copy of transaction adapter bound to ctx.
+func (a *TxAdapter) WithContext(ctx context.Context) *TxAdapter {
+	tx := *a
+	tx.crudAdapter = a.withContext(ctx)
+
+	return &tx
 }
 
-// Commit commits transaction.
+// IgnoreNotFound returns a copy of transaction adapter, which doesn't return ErrNotFound
+// on update or delete of missing row.
+func (a *TxAdapter) IgnoreNotFound() *TxAdapter {
+	tx := *a
+	tx.crudAdapter = a.withIgnoreNotFound()
+
+	return &tx
+}
+
+// Commit commits transaction. Commit of nested transaction releases its savepoint,
+// so the changes are committed (or discarded) along with outer transaction.
 func (a *TxAdapter) Commit() error {
-	return a.con.(*pgx.Tx).Commit()
+	if a.savepoint != "" {
+		return a.Release(a.savepoint)
+	}
+
+	err := a.con.(*pgx.Tx).CommitEx(a.getContext())
+	a.releaseConn(err)
+
+	return err
 }
 
-// Rollback performs transaction rollback.
+// Rollback performs transaction rollback. Rollback ignores adapter context,
+// so the transaction is not left open in case the context is already done.
+// Rollback of nested transaction discards only changes made since it was started.
 func (a *TxAdapter) Rollback() error {
-	return a.con.(*pgx.Tx).Rollback()
+	if a.savepoint != "" {
+		return a.WithContext(context.Background()).rollbackNested()
+	}
+
+	err := a.con.(*pgx.Tx).Rollback()
+	a.releaseConn(err)
+
+	return err
+}
+
+// releaseConn returns connection of top level transaction to pool, once the transaction is finished
+// with Commit or Rollback returned err.
+func (a *TxAdapter) releaseConn(err error) {
+	if err != pgx.ErrTxClosed {
+		a.db.pool.Release(a.poolCon)
+	}
 }
 
 // MigrationAdapter handles operations with postgres under transaction.
@@ -41,6 +99,11 @@ type MigrationAdapter struct {
 	*crudAdapter
 }
 
+// WithContext returns a copy of migration adapter bound to ctx.
+func (a *MigrationAdapter) WithContext(ctx context.Context) *MigrationAdapter {
+	return &MigrationAdapter{crudAdapter: a.withContext(ctx)}
+}
+
 // ExecFile executes sql file.
 func (a *MigrationAdapter) ExecFile(fileName string) error {
 	filePath := getMigrationPath() + filepath.Base(fileName)
@@ -60,9 +123,9 @@ func (a *MigrationAdapter) ExecFile(fileName string) error {
 
 // Exec executes raw query.
 func (a *MigrationAdapter) Exec(sql string, args ...interface{}) error {
-	cmdTag, err := a.con.Exec(sql, args...)
+	_, err := a.con.ExecEx(a.getContext(), sql, nil, args...)
 	if err != nil {
-		return fmt.Errorf("error: (%v), cmdTag (%s)", err, cmdTag)
+		return wrapError("exec", err)
 	}
 	return nil
 }
@@ -78,11 +141,11 @@ func (a *MigrationAdapter) MustCreateTable(structPtr interface{}) {
 // CreateTable creates table from struct.
 func (a *MigrationAdapter) CreateTable(structPtr interface{}) error {
 	createTableSQL := GenerateSchema(structPtr)
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(createTableSQL)
 	}
 	// TODO decide what to do with cmdTag aka rows created (first param)
-	_, err := a.con.Exec(createTableSQL)
+	_, err := a.con.ExecEx(a.getContext(), createTableSQL, nil)
 	return err
 }
 
@@ -93,7 +156,85 @@ type Adapter struct {
 
 // crudAdapter handles basic operations with db.
 type crudAdapter struct {
+	db  *DB
 	con connection
+
+	// ctx is passed down to every query, allowing to cancel it or set a deadline.
+	ctx context.Context
+
+	// ignoreNotFound disables ErrNotFound check on update or delete by primary key.
+	ignoreNotFound bool
+
+	// usePrimary disables routing of reads to read replicas.
+	usePrimary bool
+
+	// tx is a transaction bound to adapter context (see ContextWithTx), which adapter operations join.
+	tx *TxAdapter
+}
+
+// getContext returns adapter context, or background context if adapter isn't bound to any.
+func (a *crudAdapter) getContext() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+
+	return a.ctx
+}
+
+// withContext returns a copy of adapter bound to ctx.
+func (a *crudAdapter) withContext(ctx context.Context) *crudAdapter {
+	if ctx == nil {
+		panic("pgc: nil context")
+	}
+	c := *a
+	c.ctx = ctx
+
+	return &c
+}
+
+// withIgnoreNotFound returns a copy of adapter, which doesn't check whether update or delete affected any row.
+func (a *crudAdapter) withIgnoreNotFound() *crudAdapter {
+	c := *a
+	c.ignoreNotFound = true
+
+	return &c
+}
+
+// withPrimary returns a copy of adapter, which performs reads on primary db.
+func (a *crudAdapter) withPrimary() *crudAdapter {
+	c := *a
+	c.usePrimary = true
+
+	return &c
+}
+
+// readCon returns connection for read queries: read replica in case adapter works with db pool,
+// or the adapter connection itself, e.g. in case of transaction.
+func (a *crudAdapter) readCon() connection {
+	if a.usePrimary || a.con != connection(a.db.pool) {
+		return a.con
+	}
+
+	return a.db.readPool()
+}
+
+// lockingFor returns adapter performing query stmt: query locking rows is performed on primary db,
+// since read replicas don't allow row locks.
+func (a *crudAdapter) lockingFor(stmt *pgcq.Query) *crudAdapter {
+	if stmt.Locking() && !a.usePrimary {
+		return a.withPrimary()
+	}
+
+	return a
+}
+
+// checkFound returns ErrNotFound if no rows were affected, unless adapter ignores missing rows.
+func (a *crudAdapter) checkFound(tag pgx.CommandTag) error {
+	if !a.ignoreNotFound && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
 }
 
 // mustAdapter allows panicing during pgc operations.
@@ -102,19 +243,35 @@ type mustAdapter struct {
 }
 
 type connection interface {
-	Exec(sql string, arguments ...interface{}) (commandTag pgx.CommandTag, err error)
-	Query(sql string, args ...interface{}) (*pgx.Rows, error)
-	QueryRow(sql string, args ...interface{}) *pgx.Row
+	ExecEx(ctx context.Context, sql string, options *pgx.QueryExOptions, arguments ...interface{}) (commandTag pgx.CommandTag, err error)
+	QueryEx(ctx context.Context, sql string, options *pgx.QueryExOptions, args ...interface{}) (*pgx.Rows, error)
+	QueryRowEx(ctx context.Context, sql string, options *pgx.QueryExOptions, args ...interface{}) *pgx.Row
+	CopyFrom(tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int, error)
+}
+
+// WithContext returns a copy of adapter bound to ctx. Every operation of the returned adapter
+// (including transactions started by it) is cancelled once ctx is done or its deadline is exceeded.
+// In case transaction of the same db is bound to ctx (see ContextWithTx), operations of the returned adapter
+// are performed within that transaction, and Begin starts nested transaction.
+func (a *Adapter) WithContext(ctx context.Context) *Adapter {
+	return &Adapter{&mustAdapter{a.withContext(ctx).joinTx()}}
+}
+
+// IgnoreNotFound returns a copy of adapter (fire-and-forget mode), which doesn't return ErrNotFound
+// when update or delete by primary key doesn't affect any row.
+func (a *Adapter) IgnoreNotFound() *Adapter {
+	return &Adapter{&mustAdapter{a.withIgnoreNotFound()}}
+}
+
+// Primary returns a copy of adapter, which always reads from primary db instead of read replicas,
+// e.g. in order to read just written data.
+func (a *Adapter) Primary() *Adapter {
+	return &Adapter{&mustAdapter{a.withPrimary()}}
 }
 
 // Begin begins new transaction.
 func (a *Adapter) Begin() (*TxAdapter, error) {
-	con, err := getConn().Begin()
-	if err != nil {
-		return nil, err
-	}
-
-	return &TxAdapter{crudAdapter: &crudAdapter{con: con}}, nil
+	return a.BeginTx(nil)
 }
 
 // MustInsert ensures structs are inserted without errors, panics othervise.
@@ -129,77 +286,597 @@ func (a *mustAdapter) MustInsert(structPtrs ...interface{}) {
 // Insert inserts one or more struct into db. If no options specied, struct will be updated by primary key.
 // Limit of items to insert at once is 1000 items.
 func (a *crudAdapter) Insert(structPtrs ...interface{}) error {
+	model, items, err := prepareInsert(structPtrs)
+	if err != nil {
+		return err
+	}
+	args := getInsertArgs(model, model.Fields, structPtrs)
+	tmplData := map[string]interface{}{
+		"model":  model,
+		"fields": model.Fields,
+		"Items":  items,
+	}
+	insertSQL := renderTemplate(tmplData, insertTemplate)
+	if a.db.config.LogQueries {
+		fmt.Println(insertSQL)
+	}
+
+	_, err = a.con.ExecEx(a.getContext(), insertSQL, nil, args...)
+	if err != nil {
+		return wrapError("insert", err)
+	}
+
+	return nil
+}
+
+// Returning describes which columns InsertReturning omits from insert and scans back into structs.
+// Primary key is always returned, so returned rows are matched to structs by it, unless primary key is generated.
+type Returning struct {
+	// Columns are returned by insert and scanned back into structs, all columns returned if empty.
+	Columns []string
+	// Generated are columns generated by db (defaults, serial columns, now() etc.), which are omitted from insert,
+	// so db generates their values. Generated columns are always returned.
+	Generated []string
+}
+
+// MustInsertReturning ensures structs are inserted without errors, panics othervise.
+// Limit of items to insert at once is 1000 items.
+func (a *mustAdapter) MustInsertReturning(returning Returning, structPtrs ...interface{}) {
+	err := a.InsertReturning(returning, structPtrs...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// InsertReturning inserts one or more struct into db and scans values stored by db back into structs.
+// Returned columns and columns generated by db are specified separately (see Returning),
+// so returning a column doesn't prevent it from being inserted, e.g. when rows are changed by triggers.
+// Limit of items to insert at once is 1000 items.
+func (a *crudAdapter) InsertReturning(returning Returning, structPtrs ...interface{}) error {
+	model, items, err := prepareInsert(structPtrs)
+	if err != nil {
+		return err
+	}
+	returningFields, err := getReturningFields(model, returning.Columns)
+	if err != nil {
+		return err
+	}
+	pk, err := model.getFieldsStrict([]string{model.PKName})
+	if err != nil {
+		return err
+	}
+	// returned rows are matched to structs by primary key, which is set by client
+	returningFields = appendMissingFields(returningFields, pk)
+	pkField := pk[0]
+	fields := model.Fields
+	if len(returning.Generated) != 0 {
+		generatedFields, err := model.getFieldsStrict(returning.Generated)
+		if err != nil {
+			return err
+		}
+		returningFields = appendMissingFields(returningFields, generatedFields)
+		fields = make([]*field, 0, len(model.Fields))
+	FieldsLoop:
+		for _, f := range model.Fields {
+			for _, gf := range generatedFields {
+				if gf == f {
+					if f == pkField {
+						// primary key generated by db isn't known in advance, so rows are matched by order
+						pkField = nil
+					}
+					continue FieldsLoop
+				}
+			}
+			fields = append(fields, f)
+		}
+		if len(fields) == 0 {
+			return errors.New("no columns left to insert")
+		}
+	}
+	args := getInsertArgs(model, fields, structPtrs)
+	tmplData := map[string]interface{}{
+		"model":     model,
+		"fields":    fields,
+		"Items":     items,
+		"returning": returningFields,
+	}
+	insertSQL := renderTemplate(tmplData, insertReturningTemplate)
+	if a.db.config.LogQueries {
+		fmt.Println(insertSQL)
+	}
+
+	con, release, err := acquireConn(a.con)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := con.QueryEx(a.getContext(), insertSQL, nil, args...)
+	if err != nil {
+		return wrapError("insert", err)
+	}
+	if err := scanReturning(rows, returningFields, structPtrs, pkField); err != nil {
+		return wrapError("insert", err)
+	}
+
+	return nil
+}
+
+// appendMissingFields appends to fields ones from extra, which aren't there yet.
+func appendMissingFields(fields, extra []*field) []*field {
+ExtraLoop:
+	for _, ef := range extra {
+		for _, f := range fields {
+			if f == ef {
+				continue ExtraLoop
+			}
+		}
+		fields = append(fields, ef)
+	}
+
+	return fields
+}
+
+// getReturningFields returns fields for returning clause, all model fields returned if no columns specified.
+func getReturningFields(mod *model, columns []string) ([]*field, error) {
+	if len(columns) == 0 {
+		return mod.Fields, nil
+	}
+
+	return mod.getFieldsStrict(columns)
+}
+
+// scanReturning scans rows returned by a statement into structs. In case pk field is given (it needs to be returned),
+// rows are matched to structs by primary key, since postgres doesn't guarantee order of returned rows,
+// otherwise rows are scanned into structs in the same order.
+func scanReturning(rows *pgx.Rows, fields []*field, structPtrs []interface{}, pk *field) error {
+	defer rows.Close()
+
+	var byPK map[interface{}]reflect.Value
+	if pk != nil {
+		byPK = make(map[interface{}]reflect.Value, len(structPtrs))
+		for _, structPtr := range structPtrs {
+			rowModel := reflect.ValueOf(structPtr).Elem()
+			byPK[rowModel.Field(pk.FieldPos).Interface()] = rowModel
+		}
+	}
+
+	var rowNum int
+	for rows.Next() {
+		if rowNum >= len(structPtrs) {
+			return errors.New("more rows returned than expected")
+		}
+		rowModel := reflect.ValueOf(structPtrs[rowNum]).Elem()
+		if pk != nil {
+			// it's unknown which struct the row belongs to until primary key is scanned
+			rowModel = reflect.New(rowModel.Type()).Elem()
+		}
+		valAddrs := make([]interface{}, 0, len(fields))
+		for _, f := range fields {
+			valAddrs = append(valAddrs, rowModel.Field(f.FieldPos).Addr().Interface())
+		}
+		if err := rows.Scan(valAddrs...); err != nil {
+			return err
+		}
+		if pk != nil {
+			target, ok := byPK[rowModel.Field(pk.FieldPos).Interface()]
+			if !ok {
+				return fmt.Errorf("returned row with primary key (%v) doesn't match any struct", rowModel.Field(pk.FieldPos).Interface())
+			}
+			for _, f := range fields {
+				target.Field(f.FieldPos).Set(rowModel.Field(f.FieldPos))
+			}
+		}
+		rowNum++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if rowNum != len(structPtrs) {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// prepareInsert parses structs going to be inserted at once, returns their model and parsed items.
+func prepareInsert(structPtrs []interface{}) (*model, []interface{}, error) {
 	if len(structPtrs) == 0 {
-		return errors.New("nothing to insert")
+		return nil, nil, errors.New("nothing to insert")
 	}
 	if len(structPtrs) > LimitInsert {
-		return fmt.Errorf("insertion of more than (%d) items not allowed", LimitInsert)
+		return nil, nil, fmt.Errorf("insertion of more than (%d) items not allowed", LimitInsert)
 	}
 
-	var (
-		model *model
-		args  []interface{}
-	)
+	var model *model
 	items := make([]interface{}, 0, len(structPtrs))
 	for i, structPtr := range structPtrs {
 		mod := parseModel(structPtr, true)
-		rowModel := reflect.ValueOf(structPtr)
 		items = append(items, mod)
 		if i == 0 {
 			model = mod
-			args = make([]interface{}, 0, len(mod.Fields)*len(structPtrs))
 		}
 
 		if i != 0 && mod.TableName != model.TableName {
-			return errors.New("cannot insert items from different tables")
+			return nil, nil, errors.New("cannot insert items from different tables")
+		}
+	}
+
+	return model, items, nil
+}
+
+// getInsertArgs returns values of given fields of each struct as a flat list of query args.
+func getInsertArgs(mod *model, fields []*field, structPtrs []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(fields)*len(structPtrs))
+	for _, structPtr := range structPtrs {
+		args = append(args, mod.getVals(reflect.ValueOf(structPtr), fields)...)
+	}
+
+	return args
+}
+
+// MustBulkInsert ensures structs are bulk inserted without errors, panics othervise. Returns number of inserted rows.
+func (a *mustAdapter) MustBulkInsert(src interface{}) int64 {
+	num, err := a.BulkInsert(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return num
+}
+
+// BulkInsert inserts large amount of structs using postgres copy protocol, returns number of inserted rows.
+// src is expected to be a slice of structs (or struct pointers), or a channel of them, in which case
+// rows are inserted until the channel is closed. There is no limit of items to insert.
+// Adapter context is checked between rows, so copying is aborted once the context is done.
+func (a *crudAdapter) BulkInsert(src interface{}) (int64, error) {
+	rowSrc, err := newCopySource(a.getContext(), src)
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, 0, len(rowSrc.mod.Fields))
+	for _, f := range rowSrc.mod.Fields {
+		columns = append(columns, f.PGName)
+	}
+	if a.db.config.LogQueries {
+		fmt.Printf("COPY \"%s\" (%s) FROM STDIN\n", rowSrc.mod.TableName, strings.Join(columns, ", "))
+	}
+
+	num, err := a.con.CopyFrom(pgx.Identifier{rowSrc.mod.TableName}, columns, rowSrc)
+	if err != nil {
+		return int64(num), wrapError("bulk insert", err)
+	}
+
+	return int64(num), nil
+}
+
+// copySource feeds pgx copy protocol with structs from a slice or a channel.
+type copySource struct {
+	ctx context.Context
+	mod *model
+
+	src     reflect.Value
+	pos     int
+	current reflect.Value
+	err     error
+}
+
+func newCopySource(ctx context.Context, src interface{}) (*copySource, error) {
+	rv := reflect.ValueOf(src)
+	if rv.Kind() != reflect.Slice && (rv.Kind() != reflect.Chan || rv.Type().ChanDir()&reflect.RecvDir == 0) {
+		return nil, errors.New("please pass a slice or a channel of structs for BulkInsert.src")
+	}
+
+	elemType := rv.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, errors.New("please pass a slice or a channel of structs for BulkInsert.src")
+	}
+
+	return &copySource{
+		ctx: ctx,
+		mod: parseModel(reflect.New(elemType).Interface(), true),
+		src: rv,
+	}, nil
+}
+
+// Next implements pgx.CopyFromSource.
+func (s *copySource) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+
+	var item reflect.Value
+	if s.src.Kind() == reflect.Slice {
+		if s.pos >= s.src.Len() {
+			return false
 		}
-		args = append(args, mod.getVals(rowModel, mod.Fields)...)
+		item = s.src.Index(s.pos)
+	} else {
+		chosen, recv, ok := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: s.src},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ctx.Done())},
+		})
+		if chosen == 1 {
+			s.err = s.ctx.Err()
+			return false
+		}
+		if !ok {
+			return false
+		}
+		item = recv
+	}
+	s.pos++
+
+	if item.Kind() == reflect.Ptr && item.IsNil() {
+		s.err = fmt.Errorf("nil struct pointer at position (%d)", s.pos-1)
+		return false
 	}
+	s.current = item
+
+	return true
+}
+
+// Values implements pgx.CopyFromSource.
+func (s *copySource) Values() ([]interface{}, error) {
+	return s.mod.getVals(s.current, s.mod.Fields), nil
+}
+
+// Err implements pgx.CopyFromSource.
+func (s *copySource) Err() error {
+	return s.err
+}
+
+// Conflict describes how upsert deals with rows that already exist.
+type Conflict struct {
+	// Columns is a conflict target, primary key is used if neither Columns nor Constraint specified.
+	Columns []string
+	// Constraint is a name of unique constraint used as a conflict target (ON CONFLICT ON CONSTRAINT).
+	Constraint string
+
+	// DoNothing tells to skip conflicting rows.
+	DoNothing bool
+	// Update is a list of columns to be updated on conflict.
+	// If empty, all columns except primary key and conflict target are updated.
+	// Version column (pgc:"version") of updated row is incremented, so it cannot be listed in Update.
+	Update []string
+}
+
+// MustUpsert ensures structs are upserted without errors, panics othervise.
+// Limit of items to upsert at once is 1000 items.
+func (a *mustAdapter) MustUpsert(conflict Conflict, structPtrs ...interface{}) {
+	err := a.Upsert(conflict, structPtrs...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Upsert inserts one or more struct into db, resolving conflicts as described by conflict param
+// (INSERT ... ON CONFLICT). By default conflicting rows are updated with all new values by primary key.
+// Limit of items to upsert at once is 1000 items.
+func (a *crudAdapter) Upsert(conflict Conflict, structPtrs ...interface{}) error {
+	model, items, err := prepareInsert(structPtrs)
+	if err != nil {
+		return err
+	}
+	if conflict.DoNothing && len(conflict.Update) != 0 {
+		return errors.New("cannot specify update columns with do nothing conflict action")
+	}
+	if conflict.Constraint != "" && len(conflict.Columns) != 0 {
+		return errors.New("cannot specify both conflict columns and constraint")
+	}
+
+	var conflictFields []*field
+	if conflict.Constraint == "" {
+		conflictColumns := conflict.Columns
+		if len(conflictColumns) == 0 {
+			conflictColumns = []string{model.PKName}
+		}
+		if conflictFields, err = model.getFieldsStrict(conflictColumns); err != nil {
+			return err
+		}
+	}
+
+	var (
+		updateFields []*field
+		version      *field
+	)
+	if !conflict.DoNothing {
+		// version of updated row is incremented, rather than overwritten with struct version
+		version = model.getVersionField()
+		if len(conflict.Update) != 0 {
+			if updateFields, err = model.getFieldsStrict(conflict.Update); err != nil {
+				return err
+			}
+			for _, f := range updateFields {
+				if f == version {
+					return fmt.Errorf("cannot update version column (%s), it is incremented automatically", f.PGName)
+				}
+			}
+		} else {
+			updateFields = make([]*field, 0, len(model.Fields))
+		FieldsLoop:
+			for _, f := range model.Fields {
+				if f.PGName == model.PKName || f == version {
+					continue
+				}
+				for _, cf := range conflictFields {
+					if cf == f {
+						continue FieldsLoop
+					}
+				}
+				updateFields = append(updateFields, f)
+			}
+		}
+	}
+
+	args := getInsertArgs(model, model.Fields, structPtrs)
 	tmplData := map[string]interface{}{
-		"model": model,
-		"Items": items,
+		"model":          model,
+		"fields":         model.Fields,
+		"Items":          items,
+		"constraint":     conflict.Constraint,
+		"conflictFields": conflictFields,
+		"updateFields":   updateFields,
+		"version":        version,
 	}
-	insertSQL := renderTemplate(tmplData, insertTemplate)
-	if cfg.LogQueries {
-		fmt.Println(insertSQL)
+	upsertSQL := renderTemplate(tmplData, upsertTemplate)
+	if a.db.config.LogQueries {
+		fmt.Println(upsertSQL)
 	}
 
-	tag, err := a.con.Exec(insertSQL, args...)
+	_, err = a.con.ExecEx(a.getContext(), upsertSQL, nil, args...)
 	if err != nil {
-		return fmt.Errorf("insert error: %v, cmdTag: %s", err, tag)
+		return wrapError("upsert", err)
 	}
 
 	return nil
 }
 
 // MustUpdate ensures struct will be updated without errors, panics othervise.
+// Panics with ErrNotFound as well, if there is no row with such primary key.
 func (a *mustAdapter) MustUpdate(structPtr interface{}) {
 	err := a.Update(structPtr)
-	/*
-		Add later:
-		if commandTag.RowsAffected() != 1 {
-			return errors.New("No row found to delete")
-		}
-	*/
 	if err != nil {
 		panic(err)
 	}
 }
 
-// Update updates struct by primary key.
+// Update updates struct by primary key. Returns ErrNotFound if there is no row with such primary key.
 func (a *crudAdapter) Update(structPtr interface{}) error {
+	return a.update(structPtr, nil, nil)
+}
+
+// MustUpdateColumns ensures struct columns will be updated without errors, panics othervise.
+func (a *mustAdapter) MustUpdateColumns(structPtr interface{}, columns ...string) {
+	err := a.UpdateColumns(structPtr, columns...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// UpdateColumns updates only specified columns of struct by primary key, leaving other columns untouched.
+// Returns ErrNotFound if there is no row with such primary key.
+func (a *crudAdapter) UpdateColumns(structPtr interface{}, columns ...string) error {
+	if len(columns) == 0 {
+		return errors.New("columns for update cannot be empty")
+	}
 	mod := parseModel(structPtr, true)
-	fieldsNoPK := mod.GetFieldsNoPK(nil)
+	fields, err := mod.getFieldsStrict(columns)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.PGName == mod.PKName {
+			return fmt.Errorf("cannot update primary key column (%s)", f.PGName)
+		}
+		if f.PGName == mod.VersionName {
+			return fmt.Errorf("cannot update version column (%s), it is incremented automatically", f.PGName)
+		}
+	}
+
+	return a.update(structPtr, fields, nil)
+}
+
+// MustUpdateReturning ensures struct will be updated without errors, panics othervise.
+func (a *mustAdapter) MustUpdateReturning(structPtr interface{}, columns ...string) {
+	err := a.UpdateReturning(structPtr, columns...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// UpdateReturning updates struct by primary key and scans values of given columns, as they were stored
+// by db (e.g. changed by triggers), back into struct. If no columns specified, all columns returned.
+func (a *crudAdapter) UpdateReturning(structPtr interface{}, columns ...string) error {
+	returning, err := getReturningFields(parseModel(structPtr, true), columns)
+	if err != nil {
+		return err
+	}
+
+	return a.update(structPtr, nil, returning)
+}
+
+// update updates struct by primary key, scanning returning fields back into struct if any specified.
+// If fields are empty, all fields except primary key are updated.
+// In case model has version field, row is updated only if its version matches struct version,
+// otherwise ErrStaleObject returned.
+func (a *crudAdapter) update(structPtr interface{}, fields []*field, returning []*field) error {
+	mod := parseModel(structPtr, true)
+	version := mod.getVersionField()
+	if len(fields) == 0 {
+		fields = make([]*field, 0, len(mod.Fields))
+		for _, f := range mod.GetFieldsNoPK(nil) {
+			if f != version {
+				fields = append(fields, f)
+			}
+		}
+	}
 
 	rowModel := reflect.ValueOf(structPtr)
-	args := append(mod.getVals(rowModel, fieldsNoPK), mod.getPK(rowModel))
-	updateSQL := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, fmt.Sprintf("%s WHERE \"{{.mod.PKName}}\" = $%d;", updateTemplate, len(args)))
-	if cfg.LogQueries {
+	args := append(mod.getVals(rowModel, fields), mod.getPK(rowModel))
+	updateTpl := fmt.Sprintf("%s WHERE \"{{.mod.PKName}}\" = $%d", updateTemplate, len(args))
+	if version != nil {
+		args = append(args, mod.getVals(rowModel, []*field{version})...)
+		updateTpl += fmt.Sprintf(" AND {{.version.PGNameQuoted}} = $%d", len(args))
+	}
+	if len(returning) != 0 {
+		updateTpl += returningTemplate
+	}
+	updateSQL := renderTemplate(Map{"mod": mod, "fields": fields, "version": version, "returning": returning}, updateTpl+";")
+	if a.db.config.LogQueries {
 		fmt.Println(updateSQL)
 	}
-	tag, err := a.con.Exec(updateSQL, args...)
-	if err != nil {
-		return fmt.Errorf("update error: %v, cmdTag: %s", err, tag)
+
+	var versionReturned bool
+	if len(returning) != 0 {
+		con, release, err := acquireConn(a.con)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		rows, err := con.QueryEx(a.getContext(), updateSQL, nil, args...)
+		if err != nil {
+			return wrapError("update", err)
+		}
+		if err := scanReturning(rows, returning, []interface{}{structPtr}, nil); err != nil {
+			if err == pgx.ErrNoRows {
+				if version != nil {
+					return ErrStaleObject
+				}
+				if !a.ignoreNotFound {
+					return ErrNotFound
+				}
+				return nil
+			}
+			return wrapError("update", err)
+		}
+		for _, f := range returning {
+			if f == version {
+				versionReturned = true
+			}
+		}
+	} else {
+		tag, err := a.con.ExecEx(a.getContext(), updateSQL, nil, args...)
+		if err != nil {
+			return wrapError("update", err)
+		}
+		if version != nil && tag.RowsAffected() == 0 {
+			return ErrStaleObject
+		}
+		if err := a.checkFound(tag); err != nil {
+			return err
+		}
+	}
+	if version != nil && !versionReturned {
+		mod.incrementVersion(rowModel)
 	}
 
 	return nil
@@ -253,15 +930,21 @@ func (a *crudAdapter) UpdateRows(structPtr interface{}, dataMap Map, opts ...pgc
 		return 0, errors.New("query options cannot be empty")
 	}
 
+	// bump version of updated rows, unless it's updated explicitly
+	var version *field
+	if _, ok := dataMap[mod.VersionName]; !ok {
+		version = mod.getVersionField()
+	}
+
 	updateTpl := updateTemplate + " " + stmt.Query + ";"
-	updateSQL := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, updateTpl)
-	if cfg.LogQueries {
+	updateSQL := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK, "version": version}, updateTpl)
+	if a.db.config.LogQueries {
 		fmt.Println(updateSQL)
 	}
 
-	tag, err := a.con.Exec(updateSQL, stmt.Args...)
+	tag, err := a.con.ExecEx(a.getContext(), updateSQL, nil, stmt.Args...)
 	if err != nil {
-		return 0, fmt.Errorf("update error: %v, cmdTag: %s", err, tag)
+		return 0, wrapError("update", err)
 	}
 
 	return tag.RowsAffected(), nil
@@ -283,6 +966,11 @@ func (a *crudAdapter) Select(destSlicePtr interface{}, opts ...pgcq.Option) erro
 		return err
 	}
 
+	return a.selectStmt(destSlicePtr, stmt)
+}
+
+// selectStmt performs select of built query into destSlicePtr.
+func (a *crudAdapter) selectStmt(destSlicePtr interface{}, stmt *pgcq.Query) error {
 	mod, sliceValElement, sliceTypeElement, err := parseDestSlice(destSlicePtr)
 	if err != nil {
 		return err
@@ -294,11 +982,70 @@ func (a *crudAdapter) Select(destSlicePtr interface{}, opts ...pgcq.Option) erro
 	}
 
 	finalSQL := renderTemplate(Map{"mod": mod, "fields": fields, "joins": stmt.Joins, "joinFields": joinFields, "joinMods": joinMods}, selectBaseTemplate) + " " + stmt.Query + ";"
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(finalSQL)
 	}
 
-	return rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement, sliceTypeElement, a.con, stmt.Args...)
+	return rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement, sliceTypeElement, a.lockingFor(stmt), stmt.Args...)
+}
+
+// MustSelectPage ensures page select will not produce any error, panics othervise. Returns the next page cursor.
+func (a *mustAdapter) MustSelectPage(destSlicePtr interface{}, opts ...pgcq.Option) pgcq.Cursor {
+	next, err := a.SelectPage(destSlicePtr, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return next
+}
+
+// SelectPage performs select with keyset pagination, which requires pgcq.Seek option, and returns the cursor
+// of the next page. Empty next cursor means there are no more pages. Primary key is appended to seek columns
+// (if it's not the last one already) to keep pagination stable for rows with the same values.
+// destSlicePtr parameter expects pointer to a slice
+func (a *crudAdapter) SelectPage(destSlicePtr interface{}, opts ...pgcq.Option) (next pgcq.Cursor, err error) {
+	mod, sliceValElement, _, err := parseDestSlice(destSlicePtr)
+	if err != nil {
+		return "", err
+	}
+	if mod.PKName != "" {
+		// cursor includes primary key, so it needs to be appended before the cursor is decoded
+		opts = append(opts[:len(opts):len(opts)], pgcq.SeekTieBreaker(mod.PKName))
+	}
+	stmt, err := pgcq.Build(opts, pgcq.OpSelect)
+	if err != nil {
+		return "", err
+	}
+	if stmt.Keyset == nil {
+		return "", errors.New("seek option is required for select page")
+	}
+
+	keysetFields, err := mod.getFieldsStrict(stmt.Keyset.Columns)
+	if err != nil {
+		return "", err
+	}
+	fetchedFields := mod.getFields(stmt.Columns)
+KeysetLoop:
+	for _, kf := range keysetFields {
+		for _, f := range fetchedFields {
+			if f == kf {
+				continue KeysetLoop
+			}
+		}
+		return "", fmt.Errorf("seek column (%s) needs to be fetched", kf.PGName)
+	}
+
+	prevLen := sliceValElement.Len()
+	if err := a.selectStmt(destSlicePtr, stmt); err != nil {
+		return "", err
+	}
+	fetchedLen := sliceValElement.Len() - prevLen
+	if stmt.Limit() == 0 || fetchedLen < stmt.Limit() {
+		return "", nil
+	}
+
+	lastRow := sliceValElement.Index(sliceValElement.Len() - 1)
+	return pgcq.EncodeCursor(mod.getVals(lastRow, keysetFields)...)
 }
 
 // MustSelectCustomData ensures select will not produce any error, panics othervise.
@@ -335,11 +1082,11 @@ func (a *crudAdapter) SelectCustomData(model interface{}, destSlicePtr interface
 	finalSQL := renderTemplate(Map{"mod": mod, "fields": customFields}, selectBaseTemplate)
 
 	finalSQL += " " + stmt.Query + ";"
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(finalSQL)
 	}
 
-	return rawSelect(finalSQL, stmt.Columns, nil, nil, false, sliceValElement, sliceTypeElement, a.con, stmt.Args...)
+	return rawSelect(finalSQL, stmt.Columns, nil, nil, false, sliceValElement, sliceTypeElement, a.lockingFor(stmt), stmt.Args...)
 }
 
 func parseDestSlice(destSlicePtr interface{}) (*model, reflect.Value, reflect.Type, error) {
@@ -390,19 +1137,27 @@ func (a *crudAdapter) Get(structPtr interface{}, opts ...pgcq.Option) (found boo
 		columns []string
 		stmt    pgcq.Query
 	)
+	mod := parseModel(structPtr, true)
+	rowModel := reflect.ValueOf(structPtr)
 	if len(opts) != 0 {
 		s, err := pgcq.Build(opts, pgcq.OpSelect)
 		if err != nil {
 			return false, err
 		}
+		if !s.AffectsRows() {
+			// options don't affect selected rows (only columns or row lock specified), so get by primary key
+			pkOpts := append([]pgcq.Option{pgcq.Equal(mod.PKName, mod.getPK(rowModel))}, opts...)
+			if s, err = pgcq.Build(pkOpts, pgcq.OpSelect); err != nil {
+				return false, err
+			}
+		}
 		stmt = *s
 		query = stmt.Query
 		args = stmt.Args
 		columns = stmt.Columns
+		a = a.lockingFor(&stmt)
 	}
-	mod := parseModel(structPtr, true)
 	fields := mod.getFields(columns)
-	rowModel := reflect.ValueOf(structPtr)
 	if len(opts) == 0 {
 		args = []interface{}{mod.getPK(rowModel)}
 	}
@@ -412,11 +1167,11 @@ func (a *crudAdapter) Get(structPtr interface{}, opts ...pgcq.Option) (found boo
 			return false, err
 		}
 		finalSQL := renderTemplate(Map{"mod": mod, "fields": fields, "joins": stmt.Joins, "joinFields": joinFields, "joinMods": joinMods}, selectBaseTemplate) + " " + stmt.Query + ";"
-		if cfg.LogQueries {
+		if a.db.config.LogQueries {
 			fmt.Println(finalSQL)
 		}
 		sliceValElement := reflect.New(reflect.SliceOf(mod.ReflectType.Elem()))
-		if err := rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement.Elem(), mod.ReflectType.Elem(), a.con, stmt.Args...); err != nil {
+		if err := rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement.Elem(), mod.ReflectType.Elem(), a, stmt.Args...); err != nil {
 			return false, err
 		}
 		if sliceValElement.Elem().Len() == 0 {
@@ -429,18 +1184,24 @@ func (a *crudAdapter) Get(structPtr interface{}, opts ...pgcq.Option) (found boo
 
 	getTpl += " " + query + ";"
 	getSQL := renderTemplate(Map{"mod": mod, "fields": fields}, getTpl)
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(getSQL)
 	}
 
-	row := a.con.QueryRow(getSQL, args...)
-
 	valAddrs := make([]interface{}, 0, len(fields))
 	for i := range fields {
 		valAddrs = append(valAddrs, rowModel.Elem().Field(fields[i].FieldPos).Addr().Interface())
 	}
 
-	err = row.Scan(valAddrs...)
+	err = a.retryRead(func() error {
+		con, release, err := acquireConn(a.readCon())
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return con.QueryRowEx(a.getContext(), getSQL, nil, args...).Scan(valAddrs...)
+	})
 	if err != nil {
 		if err.Error() != pgx.ErrNoRows.Error() {
 			return false, err
@@ -453,13 +1214,14 @@ func (a *crudAdapter) Get(structPtr interface{}, opts ...pgcq.Option) (found boo
 }
 
 // MustDelete ensures struct will be deleted without errors, panics othervise.
+// Panics with ErrNotFound as well, if there is no row with such primary key.
 func (a *mustAdapter) MustDelete(structPtr interface{}) {
 	if err := a.Delete(structPtr); err != nil {
 		panic(err)
 	}
 }
 
-// Delete deletes struct by primary key or by specified options.
+// Delete deletes struct by primary key. Returns ErrNotFound if there is no row with such primary key.
 func (a *crudAdapter) Delete(structPtr interface{}) error {
 	mod := parseModel(structPtr, true)
 	rowModel := reflect.ValueOf(structPtr)
@@ -468,16 +1230,16 @@ func (a *crudAdapter) Delete(structPtr interface{}) error {
 		return fmt.Errorf("pgc cant delete from table (%s), ID/PK not set", mod.TableName)
 	}
 	deleteSQL := renderTemplate(mod, deleteTemplate+" WHERE \"{{.PKName}}\" = $1")
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(deleteSQL)
 	}
 
-	cmdTag, err := a.con.Exec(deleteSQL, pkVal)
+	cmdTag, err := a.con.ExecEx(a.getContext(), deleteSQL, nil, pkVal)
 	if err != nil {
-		return fmt.Errorf("delete error: (%v), cmdTag (%v)", err, cmdTag)
+		return wrapError("delete", err)
 	}
 
-	return nil
+	return a.checkFound(cmdTag)
 }
 
 // MustDeleteRows ensures rows are deleted without errors, panics othervise. Returns number of affected rows.
@@ -507,13 +1269,13 @@ func (a *crudAdapter) DeleteRows(structPtr interface{}, opts ...pgcq.Option) (in
 
 	deleteTpl := deleteTemplate + " " + stmt.Query + ";"
 	deleteSQL := renderTemplate(mod, deleteTpl)
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(deleteSQL)
 	}
 
-	cmdTag, err := a.con.Exec(deleteSQL, stmt.Args...)
+	cmdTag, err := a.con.ExecEx(a.getContext(), deleteSQL, nil, stmt.Args...)
 	if err != nil {
-		return 0, fmt.Errorf("delete error: (%v), cmdTag (%v)", err, cmdTag)
+		return 0, wrapError("delete", err)
 	}
 
 	return cmdTag.RowsAffected(), nil
@@ -1,11 +1,16 @@
 package pgc_test
 
 import (
+	"context"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cliqueinc/pgc"
 	"github.com/cliqueinc/pgc/util"
+	"github.com/jackc/pgx"
 )
 
 func TestAdapter(t *testing.T) {
@@ -88,6 +93,32 @@ func TestAdapter(t *testing.T) {
 		}
 	})
 
+	t.Run("Context", func(t *testing.T) {
+		type contextUser struct {
+			ID   string
+			Name string
+		}
+		pgc.MustCreateTable(&contextUser{})
+
+		u := &contextUser{ID: util.RandomString(30), Name: util.RandomString(10)}
+		if err := pgc.WithContext(context.Background()).Insert(u); err != nil {
+			t.Fatalf("failed to insert row: %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		a := pgc.NewAdapter().WithContext(ctx)
+		if _, err := a.Get(&contextUser{ID: u.ID}); err == nil {
+			t.Errorf("get with cancelled context should fail")
+		}
+		if err := a.Insert(&contextUser{ID: util.RandomString(30)}); err == nil {
+			t.Errorf("insert with cancelled context should fail")
+		}
+		if _, err := a.Begin(); err == nil {
+			t.Errorf("begin with cancelled context should fail")
+		}
+	})
+
 	if err := pgc.CreateTable(fu); err != nil {
 		t.Fatalf("failed to create schema: %s", err)
 	}
@@ -234,3 +265,422 @@ func ExampleBegin() {
 		panic(err)
 	}
 }
+
+func TestDB(t *testing.T) {
+	type fakeDBUser struct {
+		ID   string
+		Name string
+	}
+
+	rows, err := pgc.Query("SELECT current_database()")
+	if err != nil {
+		t.Fatalf("cannot get current database: %v", err)
+	}
+	var dbName string
+	for rows.Next() {
+		if err := rows.Scan(&dbName); err != nil {
+			t.Fatalf("cannot scan current database: %v", err)
+		}
+	}
+	rows.Close()
+	pgc.MustCreateTable(&fakeDBUser{})
+
+	db, err := pgc.NewDB(testConnString(dbName), &pgc.Config{MaxConnections: 5})
+	if err != nil {
+		t.Fatalf("cannot create db: %v", err)
+	}
+	defer db.Close()
+
+	a := db.NewAdapter()
+	u := &fakeDBUser{ID: util.RandomString(25), Name: "Bob"}
+	if err := a.Insert(u); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+	if found := pgc.MustGet(&fakeDBUser{ID: u.ID}); !found {
+		t.Errorf("row inserted by db adapter expected to be visible by default pool")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	if err := tx.Delete(u); err != nil {
+		t.Fatalf("failed to delete row: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+	if found := pgc.MustGet(&fakeDBUser{ID: u.ID}); found {
+		t.Errorf("row expected to be deleted")
+	}
+}
+
+func TestRunInTx(t *testing.T) {
+	type fakeTxUser struct {
+		ID   string
+		Name string
+	}
+	pgc.MustCreateTable(&fakeTxUser{})
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		u := &fakeTxUser{ID: util.RandomString(25), Name: "Bob"}
+		err := pgc.RunInTx(ctx, nil, func(tx *pgc.TxAdapter) error {
+			return tx.Insert(u)
+		})
+		if err != nil {
+			t.Fatalf("failed to run transaction: %v", err)
+		}
+		if found := pgc.MustGet(&fakeTxUser{ID: u.ID}); !found {
+			t.Errorf("transaction changes should have been preserved")
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		u := &fakeTxUser{ID: util.RandomString(25), Name: "Bob"}
+		errFailed := errors.New("failed")
+		err := pgc.RunInTx(ctx, nil, func(tx *pgc.TxAdapter) error {
+			if err := tx.Insert(u); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if err != errFailed {
+			t.Fatalf("expected error (%v), got (%v)", errFailed, err)
+		}
+		if found := pgc.MustGet(&fakeTxUser{ID: u.ID}); found {
+			t.Errorf("transaction changes should have been discarded")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		u := &fakeTxUser{ID: util.RandomString(25), Name: "Bob"}
+		func() {
+			defer func() {
+				if p := recover(); p == nil {
+					t.Errorf("panic expected to be propagated")
+				}
+			}()
+			pgc.RunInTx(ctx, nil, func(tx *pgc.TxAdapter) error {
+				if err := tx.Insert(u); err != nil {
+					t.Fatalf("failed to insert row: %v", err)
+				}
+				panic("failed")
+			})
+		}()
+		if found := pgc.MustGet(&fakeTxUser{ID: u.ID}); found {
+			t.Errorf("transaction changes should have been discarded")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		u := &fakeTxUser{ID: util.RandomString(25), Name: "Bob"}
+		var attempts int
+		err := pgc.RunInTx(ctx, &pgc.TxOptions{IsoLevel: pgx.Serializable}, func(tx *pgc.TxAdapter) error {
+			attempts++
+			if err := tx.Insert(u); err != nil {
+				return err
+			}
+			if attempts == 1 {
+				return pgx.PgError{Code: pgc.PGECSerializationFailure}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to run transaction: %v", err)
+		}
+		if attempts != 2 {
+			t.Errorf("expected transaction to be run 2 times, was run %d times", attempts)
+		}
+		if found := pgc.MustGet(&fakeTxUser{ID: u.ID}); !found {
+			t.Errorf("transaction changes should have been preserved")
+		}
+
+		attempts = 0
+		err = pgc.RunInTx(ctx, &pgc.TxOptions{MaxAttempts: 2}, func(tx *pgc.TxAdapter) error {
+			attempts++
+			return pgx.PgError{Code: pgc.PGECDeadlockDetected}
+		})
+		if !pgc.IsDeadlockError(err) {
+			t.Errorf("expected deadlock error, got (%v)", err)
+		}
+		if attempts != 2 {
+			t.Errorf("expected transaction to be run 2 times, was run %d times", attempts)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		err := pgc.RunInTx(ctx, &pgc.TxOptions{ReadOnly: true}, func(tx *pgc.TxAdapter) error {
+			return tx.Insert(&fakeTxUser{ID: util.RandomString(25)})
+		})
+		if pgc.ErrorCode(err) != "25006" {
+			t.Errorf("expected read only transaction error, got (%v)", err)
+		}
+	})
+}
+
+func TestNestedTx(t *testing.T) {
+	type fakeNestedTxUser struct {
+		ID   string
+		Name string
+	}
+	pgc.MustCreateTable(&fakeNestedTxUser{})
+
+	tx, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	u1 := &fakeNestedTxUser{ID: util.RandomString(25), Name: "Bob"}
+	if err := tx.Insert(u1); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	nested, err := tx.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin nested transaction: %v", err)
+	}
+	u2 := &fakeNestedTxUser{ID: util.RandomString(25), Name: "Bob"}
+	if err := nested.Insert(u2); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Fatalf("failed to rollback nested transaction: %v", err)
+	}
+
+	nested, err = tx.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin nested transaction: %v", err)
+	}
+	u3 := &fakeNestedTxUser{ID: util.RandomString(25), Name: "Bob"}
+	if err := nested.Insert(u3); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+	if err := nested.Commit(); err != nil {
+		t.Fatalf("failed to commit nested transaction: %v", err)
+	}
+
+	if err := tx.Savepoint("before_update"); err != nil {
+		t.Fatalf("failed to create savepoint: %v", err)
+	}
+	u1.Name = "John"
+	if err := tx.Update(u1); err != nil {
+		t.Fatalf("failed to update row: %v", err)
+	}
+	if err := tx.RollbackTo("before_update"); err != nil {
+		t.Fatalf("failed to rollback to savepoint: %v", err)
+	}
+	if err := tx.Release("before_update"); err != nil {
+		t.Fatalf("failed to release savepoint: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+
+	u := &fakeNestedTxUser{ID: u1.ID}
+	if found := pgc.MustGet(u); !found || u.Name != "Bob" {
+		t.Errorf("expected row (%s) to be committed without update, got (%+v)", u1.ID, u)
+	}
+	if found := pgc.MustGet(&fakeNestedTxUser{ID: u2.ID}); found {
+		t.Errorf("row inserted by rolled back nested transaction should have been discarded")
+	}
+	if found := pgc.MustGet(&fakeNestedTxUser{ID: u3.ID}); !found {
+		t.Errorf("row inserted by committed nested transaction should have been preserved")
+	}
+}
+
+func TestContextWithTx(t *testing.T) {
+	type fakeCtxTxUser struct {
+		ID   string
+		Name string
+	}
+	pgc.MustCreateTable(&fakeCtxTxUser{})
+
+	tx, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	ctx := pgc.ContextWithTx(context.Background(), tx)
+	if txFromCtx, ok := pgc.TxFromContext(ctx); !ok || txFromCtx != tx {
+		t.Fatalf("expected transaction to be bound to context")
+	}
+
+	u1 := &fakeCtxTxUser{ID: util.RandomString(25), Name: "Bob"}
+	if err := pgc.WithContext(ctx).Insert(u1); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+	if found := pgc.MustGet(&fakeCtxTxUser{ID: u1.ID}); found {
+		t.Errorf("row inserted within transaction shouldn't be visible outside of it")
+	}
+	if found, err := tx.Get(&fakeCtxTxUser{ID: u1.ID}); err != nil || !found {
+		t.Errorf("row inserted with context adapter expected to be visible within transaction, err: %v", err)
+	}
+
+	u2 := &fakeCtxTxUser{ID: util.RandomString(25), Name: "Bob"}
+	err = pgc.RunInTx(ctx, nil, func(nested *pgc.TxAdapter) error {
+		if err := nested.Insert(u2); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatalf("expected nested transaction to fail")
+	}
+	if found, err := tx.Get(&fakeCtxTxUser{ID: u2.ID}); err != nil || found {
+		t.Errorf("row inserted by rolled back nested transaction should have been discarded, err: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+	if found := pgc.MustGet(&fakeCtxTxUser{ID: u1.ID}); !found {
+		t.Errorf("transaction changes should have been preserved")
+	}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	key := pgc.LockKey(util.RandomString(25))
+
+	l, err := pgc.Lock(key)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	if _, locked, err := pgc.TryLock(key); err != nil || locked {
+		t.Errorf("lock expected to be held by another session, locked: %v, err: %v", locked, err)
+	}
+	tx, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	if locked, err := tx.TryLock(key); err != nil || locked {
+		t.Errorf("lock expected to be held by another session, locked: %v, err: %v", locked, err)
+	}
+	tx.Rollback()
+
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	if err := l.Unlock(); err != pgc.ErrLockNotHeld {
+		t.Errorf("expected (%v) error on second unlock, got (%v)", pgc.ErrLockNotHeld, err)
+	}
+
+	err = pgc.WithLock(context.Background(), key, func() error {
+		tx, err := pgc.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if locked, err := tx.TryLock(key); err != nil || locked {
+			t.Errorf("lock expected to be held by WithLock, locked: %v, err: %v", locked, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to run with lock: %v", err)
+	}
+	err = pgc.WithLock(context.Background(), key, func() error {
+		// terminate lock session, so unlock fails
+		rows, err := pgc.Query(`SELECT pg_terminate_backend(pid) FROM pg_locks
+			WHERE locktype = 'advisory' AND objsubid = 1 AND ((classid::bigint << 32) | objid::bigint) = $1`, key)
+		if err != nil {
+			return err
+		}
+		rows.Close()
+		return rows.Err()
+	})
+	if err == nil {
+		t.Errorf("expected unlock error of terminated lock session")
+	}
+
+	tx, err = pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	if err := tx.Lock(key); err != nil {
+		t.Fatalf("failed to acquire transaction lock: %v", err)
+	}
+	if _, locked, err := pgc.TryLock(key); err != nil || locked {
+		t.Errorf("lock expected to be held by transaction, locked: %v, err: %v", locked, err)
+	}
+	tx.Commit()
+
+	l, locked, err := pgc.TryLock(key)
+	if err != nil || !locked {
+		t.Fatalf("lock expected to be released at the end of transaction, locked: %v, err: %v", locked, err)
+	}
+	l.Unlock()
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	channel := "pgc_test_" + strings.ToLower(util.RandomString(10))
+
+	sub, err := pgc.Subscribe(ctx, channel)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	doneCtx, doneCancel := context.WithCancel(context.Background())
+	doneCancel()
+	if _, err := pgc.Subscribe(doneCtx, channel); err != context.Canceled {
+		t.Errorf("expected (%v) error on subscribe with done context, got (%v)", context.Canceled, err)
+	}
+
+	tx, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	if err := tx.Notify(channel, "from tx"); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	select {
+	case n := <-sub.Notifications():
+		t.Fatalf("notification (%s) received before commit", n.Payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+	if err := pgc.Notify(channel, "direct"); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+
+	for _, expected := range []string{"from tx", "direct"} {
+		select {
+		case n := <-sub.Notifications():
+			if n.Channel != channel || n.Payload != expected {
+				t.Errorf("expected notification (%s) of channel (%s), got: %+v", expected, channel, n)
+			}
+		case <-ctx.Done():
+			t.Fatalf("notification (%s) wasn't received", expected)
+		}
+	}
+
+	cancel()
+	for range sub.Notifications() {
+	}
+}
+
+// testConnString returns connection string of test db, configured with the same env variables as Init.
+func testConnString(dbName string) string {
+	host := os.Getenv("POSTGRES_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	params := [][2]string{{"host", host}, {"dbname", dbName}, {"sslmode", "disable"}}
+	for _, env := range [][2]string{{"port", "POSTGRES_PORT"}, {"user", "POSTGRES_USER"}, {"password", "POSTGRES_PASSWORD"}} {
+		if val := os.Getenv(env[1]); val != "" {
+			params = append(params, [2]string{env[0], val})
+		}
+	}
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		val := strings.Replace(strings.Replace(param[1], `\`, `\\`, -1), "'", `\'`, -1)
+		parts = append(parts, param[0]+"='"+val+"'")
+	}
+
+	return strings.Join(parts, " ")
+}
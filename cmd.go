@@ -19,6 +19,7 @@ func help() {
 	fmt.Println(`Generate init: "$ pgccmd gen init StructName shortName"`)
 	fmt.Println("")
 	fmt.Println("For commands other than gen, pass -d for debug query logging")
+	fmt.Println(`Pass --dsn "postgres://user@host/db" to connect by url or DSN instead of env vars`)
 	os.Exit(-1)
 }
 
@@ -1,12 +1,14 @@
 package pgc
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx"
@@ -15,6 +17,9 @@ import (
 const (
 	DB_MAX_CONNECTIONS = 50
 
+	// DefaultReplicaCheckInterval is a default period of read replicas health checks.
+	DefaultReplicaCheckInterval = 5 * time.Second
+
 	// Default environmental var names for postgres connection string
 	ENV_VAR_NAME_PG_DB       = "POSTGRES_DB"
 	ENV_VAR_NAME_PG_HOST     = "POSTGRES_HOST"
@@ -22,11 +27,15 @@ const (
 	ENV_VAR_NAME_PG_PW       = "POSTGRES_PASSWORD"
 	ENV_VAR_NAME_PG_PORT     = "POSTGRES_PORT"
 	ENV_VAR_NAME_PG_SSL      = "POSTGRES_SSL_MODE"
+	ENV_VAR_NAME_PG_SSL_ROOT = "POSTGRES_SSL_ROOT_CERT"
+	ENV_VAR_NAME_PG_SSL_CERT = "POSTGRES_SSL_CERT"
+	ENV_VAR_NAME_PG_SSL_KEY  = "POSTGRES_SSL_KEY"
 	ENV_VAR_NAME_PG_USER     = "POSTGRES_USER"
 	ENV_VAR_NAME_LOG_QUERIES = "PGC_LOG_QUERIES"
 )
 
-var pool *pgx.ConnPool
+// defaultDB is a connection pool used by package level functions, initialized by Init* functions.
+var defaultDB *DB
 var cfg *Config
 
 func init() {
@@ -37,6 +46,9 @@ func init() {
 		EnvVarNamePW:         ENV_VAR_NAME_PG_PW,
 		EnvVarNamePort:       ENV_VAR_NAME_PG_PORT,
 		EnvVarNameSSL:        ENV_VAR_NAME_PG_SSL,
+		EnvVarNameSSLRoot:    ENV_VAR_NAME_PG_SSL_ROOT,
+		EnvVarNameSSLCert:    ENV_VAR_NAME_PG_SSL_CERT,
+		EnvVarNameSSLKey:     ENV_VAR_NAME_PG_SSL_KEY,
 		EnvVarNameUser:       ENV_VAR_NAME_PG_USER,
 		EnvVarNameLogQueries: ENV_VAR_NAME_LOG_QUERIES,
 	}
@@ -61,8 +73,98 @@ type Config struct {
 	EnvVarNamePW         string
 	EnvVarNamePort       string
 	EnvVarNameSSL        string
+	EnvVarNameSSLRoot    string
+	EnvVarNameSSLCert    string
+	EnvVarNameSSLKey     string
 	EnvVarNameUser       string
 	EnvVarNameLogQueries string
+
+	// SSLMode is one of SSLMode* constants. If empty, Init uses "require" mode when useTLS passed,
+	// "disable" otherwise, and InitFromURL/InitFromDSN use "prefer" mode unless sslmode param passed.
+	SSLMode string
+	// SSLRootCert is a file with trusted CA certificates, used by verify-ca and verify-full modes.
+	SSLRootCert string
+	// SSLCert and SSLKey are client certificate and its private key files for certificate authentication.
+	SSLCert string
+	SSLKey  string
+
+	// Connection pool settings, applied on Init. Zero values mean defaults.
+
+	// MaxConnections is a max number of pooled connections, DB_MAX_CONNECTIONS by default.
+	MaxConnections int
+	// AcquireTimeout is a max time to wait for a free connection when all connections are busy,
+	// 0 means wait forever.
+	AcquireTimeout time.Duration
+	// ConnMaxLifetime is a max time a pooled connection may be reused. Expired connection is closed
+	// once it's acquired or released by pgc, and the pool opens a new one once needed, so connections expire
+	// one by one depending on their creation time. 0 means connections are reused forever.
+	ConnMaxLifetime time.Duration
+
+	// ApplicationName, StatementTimeout and SearchPath are set for every new pooled connection.
+	ApplicationName  string
+	StatementTimeout time.Duration
+	SearchPath       string
+
+	// AfterConnect is called for every new pooled connection, after pgc settings are applied.
+	AfterConnect func(*pgx.Conn) error
+
+	// ReadReplicas are connection urls (or DSNs) of read replicas. Reads outside of transaction
+	// (Select, Get, Count etc.) are routed to healthy replicas in round-robin manner,
+	// falling back to primary if none of replicas is healthy. Pool settings are the same as primary's.
+	// Replica unreachable at db creation is marked unhealthy and is connected by health check once it's up.
+	ReadReplicas []string
+	// ReplicaCheckInterval is a period of replicas health checks, DefaultReplicaCheckInterval by default.
+	ReplicaCheckInterval time.Duration
+
+	// RetryPolicy is applied to dialing and to read operations outside of transaction, e.g. DefaultRetryPolicy.
+	// If nil, reads aren't retried, and dialing is retried 2 times with 2 seconds delay.
+	RetryPolicy *RetryPolicy
+}
+
+// sslOptions returns tls settings of config.
+func (c *Config) sslOptions() sslOptions {
+	return sslOptions{
+		Mode:     c.SSLMode,
+		RootCert: c.SSLRootCert,
+		Cert:     c.SSLCert,
+		Key:      c.SSLKey,
+	}
+}
+
+// connSettings returns session settings to be applied on every new connection.
+func (c *Config) connSettings() [][2]string {
+	var settings [][2]string
+	if c.ApplicationName != "" {
+		settings = append(settings, [2]string{"application_name", c.ApplicationName})
+	}
+	if c.StatementTimeout != 0 {
+		settings = append(settings, [2]string{"statement_timeout", fmt.Sprintf("%dms", c.StatementTimeout/time.Millisecond)})
+	}
+	if c.SearchPath != "" {
+		settings = append(settings, [2]string{"search_path", c.SearchPath})
+	}
+
+	return settings
+}
+
+// afterConnect applies config settings to a new connection.
+func (c *Config) afterConnect(con *pgx.Conn) error {
+	if settings := c.connSettings(); len(settings) != 0 {
+		calls := make([]string, 0, len(settings))
+		args := make([]interface{}, 0, len(settings)*2)
+		for _, setting := range settings {
+			args = append(args, setting[0], setting[1])
+			calls = append(calls, fmt.Sprintf("set_config($%d, $%d, false)", len(args)-1, len(args)))
+		}
+		if _, err := con.Exec("SELECT "+strings.Join(calls, ", "), args...); err != nil {
+			return fmt.Errorf("cannot apply connection settings: %v", err)
+		}
+	}
+	if c.AfterConnect != nil {
+		return c.AfterConnect(con)
+	}
+
+	return nil
 }
 
 // MustInit Initializes the Postgres connection pool or panics
@@ -79,48 +181,46 @@ func Init(dbName, dbHost, dbUserName, dbPassword string, useTLS bool, dbPort uin
 	if dbName == "" {
 		panic("You must pass a dbName to pgc.Init*")
 	}
-	if pool != nil {
-		panic("pgc connection pool has already been established")
-	}
 	pgxConfig := pgx.ConnConfig{
 		Database: dbName,
 		Host:     dbHost,
 		User:     dbUserName,
 		Password: dbPassword,
 		Port:     dbPort,
-		Dial:     Dial,
+		Dial:     cfg.dialFunc(0),
 	}
-	if useTLS {
-		pgxConfig.TLSConfig = &tls.Config{
-			InsecureSkipVerify: true,
+	ssl := cfg.sslOptions()
+	if ssl.Mode == "" {
+		ssl.Mode = SSLModeDisable
+		if useTLS {
+			ssl.Mode = SSLModeRequire
 		}
 	}
-	var err error
-	pool, err = pgx.NewConnPool(pgx.ConnPoolConfig{
-		ConnConfig:     pgxConfig,
-		MaxConnections: DB_MAX_CONNECTIONS,
-	})
+	if err := ssl.apply(&pgxConfig); err != nil {
+		return err
+	}
+
+	return initPool(pgxConfig)
+}
+
+// initPool initializes the default Postgres connection pool with pgx connection config.
+func initPool(pgxConfig pgx.ConnConfig) error {
+	if defaultDB != nil {
+		panic("pgc connection pool has already been established")
+	}
+	db, err := newDB(pgxConfig, cfg)
 	if err != nil {
 		return err
 	}
+	defaultDB = db
 	cfg.Initialized = true
 	return nil
 }
 
 // Dial wraps standard diel func and tries to reconnect to the specified address on failure.
+// Config.RetryPolicy is used instead, if set.
 func Dial(network, addr string) (net.Conn, error) {
-	netDial := (&net.Dialer{KeepAlive: 5 * time.Minute}).Dial
-	con, err := netDial(network, addr)
-	if err != nil {
-		for i := 0; i < 2; i++ {
-			time.Sleep(2 * time.Second)
-			con, err = netDial(network, addr)
-			if err == nil {
-				break
-			}
-		}
-	}
-	return con, err
+	return dial(&net.Dialer{KeepAlive: 5 * time.Minute}, &legacyDialPolicy, network, addr)
 }
 
 // Initialize PGC from environment Variables
@@ -132,8 +232,23 @@ func InitFromEnv() {
 	dbPassword := os.Getenv(cfg.EnvVarNamePW)
 	sslMode := os.Getenv(cfg.EnvVarNameSSL)
 	var useTLS bool
-	if sslMode != "" && sslMode != "disable" {
+	switch sslMode {
+	case "", SSLModeDisable:
+	case SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
 		useTLS = true
+		cfg.SSLMode = sslMode
+	default:
+		// keep compatibility with any non empty value meaning tls is on
+		useTLS = true
+	}
+	if rootCert := os.Getenv(cfg.EnvVarNameSSLRoot); rootCert != "" {
+		cfg.SSLRootCert = rootCert
+	}
+	if cert := os.Getenv(cfg.EnvVarNameSSLCert); cert != "" {
+		cfg.SSLCert = cert
+	}
+	if key := os.Getenv(cfg.EnvVarNameSSLKey); key != "" {
+		cfg.SSLKey = key
 	}
 	dbPort := os.Getenv(cfg.EnvVarNamePort)
 	var dbPortI int
@@ -152,23 +267,35 @@ func InitFromEnv() {
 	MustInit(dbName, dbHost, dbUserName, dbPassword, useTLS, uint16(dbPortI))
 }
 
-func getConn() *pgx.ConnPool {
-	if pool == nil {
+func getDefaultDB() *DB {
+	if defaultDB == nil {
 		panic("Please call pgc.Init(...) before using")
 	}
-	return pool
+	return defaultDB
 }
 
-func MustPing() {
-	var one int
-	row := getConn().QueryRow("SELECT 1")
+func getConn() *pgx.ConnPool {
+	return getDefaultDB().pool.ConnPool
+}
 
-	if row == nil {
-		panic("PGC couldnt connect to db, row was nil")
-	}
-	err := row.Scan(&one)
-	if err != nil || one != 1 {
-		panic("PGC couldnt connect to db, SELECT 1 wasnt 1")
+// Ping checks default db is reachable.
+func Ping(ctx context.Context) error {
+	return getDefaultDB().Ping(ctx)
+}
+
+// Stats returns default connection pool stats.
+func Stats() PoolStats {
+	return getDefaultDB().Stats()
+}
+
+// HealthHandler returns http handler for liveness and readiness probes of default db, see DB.HealthHandler.
+func HealthHandler() http.Handler {
+	return getDefaultDB().HealthHandler()
+}
+
+func MustPing() {
+	if err := Ping(context.Background()); err != nil {
+		panic(fmt.Sprintf("PGC couldnt connect to db: %v", err))
 	}
 }
 
@@ -201,5 +328,5 @@ func DropDB(dbName string) {
 // Can be used to close the pool if you want to drop the database (otherwise pg wont let you due to
 // active connection). Test harness uses.
 func ClosePool() {
-	getConn().Close()
+	getDefaultDB().Close()
 }
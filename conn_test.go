@@ -5,8 +5,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/jackc/pgx"
 )
 
 /*
@@ -37,6 +39,27 @@ func TestMain(m *testing.M) {
 	os.Exit(exitVal)
 }
 
+// testConnString returns connection string of test db, configured with the same env variables as Init.
+func testConnString(dbName string) string {
+	host := os.Getenv("POSTGRES_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	params := [][2]string{{"host", host}, {"dbname", dbName}, {"sslmode", "disable"}}
+	for _, env := range [][2]string{{"port", "POSTGRES_PORT"}, {"user", "POSTGRES_USER"}, {"password", "POSTGRES_PASSWORD"}} {
+		if val := os.Getenv(env[1]); val != "" {
+			params = append(params, [2]string{env[0], val})
+		}
+	}
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		val := strings.Replace(strings.Replace(param[1], `\`, `\\`, -1), "'", `\'`, -1)
+		parts = append(parts, param[0]+"='"+val+"'")
+	}
+
+	return strings.Join(parts, " ")
+}
+
 // Just to get some coverage on our init process beyond TestMain above
 func TestInitFromEnvBadPort(t *testing.T) {
 	myConfig := GetConfig()
@@ -71,3 +94,43 @@ func TestInitMissingDBName(t *testing.T) {
 func TestMustPing(t *testing.T) {
 	MustPing()
 }
+
+func TestConnSettings(t *testing.T) {
+	c := &Config{
+		ApplicationName:  "pgc_test",
+		StatementTimeout: 5 * time.Second,
+		SearchPath:       "public",
+	}
+	var afterConnectCalled bool
+	c.AfterConnect = func(*pgx.Conn) error {
+		afterConnectCalled = true
+		return nil
+	}
+
+	con, err := getConn().Acquire()
+	if err != nil {
+		t.Fatalf("cannot acquire connection: %v", err)
+	}
+	defer func() {
+		con.Exec("RESET ALL")
+		getConn().Release(con)
+	}()
+
+	if err := c.afterConnect(con); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !afterConnectCalled {
+		t.Errorf("custom after connect hook wasn't called")
+	}
+
+	var appName, statementTimeout, searchPath string
+	err = con.QueryRow("SELECT current_setting('application_name'), current_setting('statement_timeout'), current_setting('search_path')").
+		Scan(&appName, &statementTimeout, &searchPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if appName != "pgc_test" || statementTimeout != "5s" || searchPath != "public" {
+		t.Errorf("connection settings weren't applied, actual: application_name (%s), statement_timeout (%s), search_path (%s)",
+			appName, statementTimeout, searchPath)
+	}
+}
@@ -0,0 +1,276 @@
+package pgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// DB is an independent postgres connection pool with its own config, allowing to work with several databases
+// at once. Package level functions use default DB, established by Init* functions.
+type DB struct {
+	pool   *statPool
+	config *Config
+
+	// replicas are read replicas, reads are routed to them in round-robin manner.
+	replicas    []*replica
+	nextReplica uint32
+
+	// done is closed once db is closed, stopping background pool maintenance.
+	done chan struct{}
+}
+
+// replica is a read replica connection pool.
+type replica struct {
+	// pgxConfig is a replica connection config, pool is created once replica is reachable.
+	pgxConfig pgx.ConnConfig
+	mu        sync.Mutex
+	pool      *statPool
+	closed    bool
+	// healthy is set to 1 if replica passed the latest health check.
+	healthy int32
+}
+
+// getPool returns replica pool, nil if replica hasn't been reachable yet.
+func (r *replica) getPool() *statPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.pool
+}
+
+// connect creates replica pool, unless it's created already.
+func (r *replica) connect(config *Config) (*statPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, errors.New("db is closed")
+	}
+	if r.pool == nil {
+		pool, err := newPool(r.pgxConfig, config)
+		if err != nil {
+			return nil, err
+		}
+		r.pool = pool
+	}
+
+	return r.pool, nil
+}
+
+// close closes replica pool, if any.
+func (r *replica) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.pool != nil {
+		r.pool.Close()
+	}
+}
+
+// NewDB creates new connection pool from connection url or DSN (see InitFromDSN).
+// Pool, tls and logging settings are taken from config, if config is nil, default settings used.
+func NewDB(connString string, config *Config) (*DB, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	pgxConfig, err := parseConnString(connString, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDB(pgxConfig, config)
+}
+
+// MustNewDB creates new connection pool, panics in case of an error.
+func MustNewDB(connString string, config *Config) *DB {
+	db, err := NewDB(connString, config)
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// newDB creates connection pool with pgx connection config, as well as pools of read replicas if any configured.
+func newDB(pgxConfig pgx.ConnConfig, config *Config) (*DB, error) {
+	pool, err := newPool(pgxConfig, config)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{pool: pool, config: config, done: make(chan struct{})}
+
+	for _, connString := range config.ReadReplicas {
+		replicaConfig, err := parseConnString(connString, config)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid read replica connection string: %v", err)
+		}
+		// unreachable replica doesn't prevent db from being created, it's connected by health check once it's up
+		r := &replica{pgxConfig: replicaConfig}
+		if _, err := r.connect(config); err == nil {
+			r.healthy = 1
+		}
+		db.replicas = append(db.replicas, r)
+	}
+	if len(db.replicas) != 0 {
+		checkInterval := config.ReplicaCheckInterval
+		if checkInterval == 0 {
+			checkInterval = DefaultReplicaCheckInterval
+		}
+		go db.checkReplicas(checkInterval)
+	}
+
+	return db, nil
+}
+
+// newPool creates connection pool with pool settings of config.
+func newPool(pgxConfig pgx.ConnConfig, config *Config) (*statPool, error) {
+	maxConnections := config.MaxConnections
+	if maxConnections == 0 {
+		maxConnections = DB_MAX_CONNECTIONS
+	}
+
+	p := &statPool{lifetime: config.ConnMaxLifetime}
+	afterConnect := config.afterConnect
+	if p.lifetime != 0 {
+		p.created = make(map[*pgx.Conn]time.Time)
+		afterConnect = func(con *pgx.Conn) error {
+			if err := config.afterConnect(con); err != nil {
+				return err
+			}
+			p.mu.Lock()
+			// connections closed by pgx pool itself are forgotten once new ones are opened
+			for c := range p.created {
+				if !c.IsAlive() {
+					delete(p.created, c)
+				}
+			}
+			p.created[con] = time.Now()
+			p.mu.Unlock()
+
+			return nil
+		}
+	}
+
+	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{
+		ConnConfig:     pgxConfig,
+		MaxConnections: maxConnections,
+		AcquireTimeout: config.AcquireTimeout,
+		AfterConnect:   afterConnect,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.ConnPool = pool
+
+	return p, nil
+}
+
+// expire closes connection in case it exceeded max lifetime, reports whether it was closed.
+func (p *statPool) expire(con *pgx.Conn) bool {
+	if p.lifetime == 0 {
+		return false
+	}
+	p.mu.Lock()
+	created, ok := p.created[con]
+	expired := ok && time.Since(created) >= p.lifetime
+	if expired {
+		delete(p.created, con)
+	}
+	p.mu.Unlock()
+	if expired {
+		con.Close()
+	}
+
+	return expired
+}
+
+// Release returns connection to pool. Connection exceeded max lifetime is closed,
+// so the pool opens a new one once needed.
+func (p *statPool) Release(con *pgx.Conn) {
+	p.expire(con)
+	p.ConnPool.Release(con)
+}
+
+// checkReplicas checks health of read replicas every interval until db is closed.
+// Replica is considered healthy, if it responds to a simple query within interval.
+func (db *DB) checkReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, r := range db.replicas {
+				pool, err := r.connect(db.config)
+				if err == nil {
+					ctx, cancel := context.WithTimeout(context.Background(), interval)
+					var one int
+					err = pool.QueryRowEx(ctx, "SELECT 1", nil).Scan(&one)
+					cancel()
+				}
+
+				var healthy int32
+				if err == nil {
+					healthy = 1
+				}
+				atomic.StoreInt32(&r.healthy, healthy)
+			}
+		case <-db.done:
+			return
+		}
+	}
+}
+
+// readPool returns pool of the next healthy read replica, or primary pool if there are no healthy replicas.
+func (db *DB) readPool() *statPool {
+	if len(db.replicas) == 0 {
+		return db.pool
+	}
+	next := atomic.AddUint32(&db.nextReplica, 1)
+	for i := 0; i < len(db.replicas); i++ {
+		r := db.replicas[(next+uint32(i))%uint32(len(db.replicas))]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.getPool()
+		}
+	}
+
+	return db.pool
+}
+
+// Config returns db config.
+func (db *DB) Config() *Config {
+	return db.config
+}
+
+// NewAdapter creates new adapter instance, performing operations with db.
+func (db *DB) NewAdapter() *Adapter {
+	return &Adapter{&mustAdapter{&crudAdapter{db: db, con: db.pool}}}
+}
+
+// Begin begins new transaction.
+func (db *DB) Begin() (*TxAdapter, error) {
+	return db.NewAdapter().Begin()
+}
+
+// RunInTx runs fn within transaction, see Adapter.RunInTx.
+func (db *DB) RunInTx(ctx context.Context, opts *TxOptions, fn func(tx *TxAdapter) error) error {
+	return db.NewAdapter().RunInTx(ctx, opts, fn)
+}
+
+// Close closes all db connections.
+func (db *DB) Close() {
+	select {
+	case <-db.done:
+	default:
+		close(db.done)
+	}
+	for _, r := range db.replicas {
+		r.close()
+	}
+	db.pool.Close()
+}
@@ -0,0 +1,209 @@
+package pgc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cliqueinc/pgc/pgcq"
+	"github.com/jackc/pgx"
+)
+
+func TestReadReplicas(t *testing.T) {
+	var dbName string
+	if err := getConn().QueryRow("SELECT current_database()").Scan(&dbName); err != nil {
+		t.Fatalf("cannot get current database: %v", err)
+	}
+	dsn := testConnString(dbName)
+	db, err := NewDB(dsn, &Config{
+		MaxConnections:       2,
+		ReadReplicas:         []string{dsn + " application_name=replica1", dsn + " application_name=replica2"},
+		ReplicaCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("cannot create db: %v", err)
+	}
+	defer db.Close()
+
+	a := db.NewAdapter()
+	first, second := a.readCon(), a.readCon()
+	if first == connection(db.pool) || second == connection(db.pool) {
+		t.Errorf("reads expected to be routed to replicas")
+	}
+	if first == second {
+		t.Errorf("reads expected to be balanced between replicas")
+	}
+	if a.Primary().readCon() != connection(db.pool) {
+		t.Errorf("primary adapter expected to read from primary")
+	}
+
+	type fakeReplicaLock struct {
+		ID string
+	}
+	type appName struct {
+		Name string `pgc_name:"current_setting('application_name') as name"`
+	}
+	MustCreateTable(&fakeReplicaLock{})
+	MustInsert(&fakeReplicaLock{ID: "1"})
+	var names []appName
+	if err := a.SelectCustomData(&fakeReplicaLock{}, &names, pgcq.ForUpdate()); err != nil {
+		t.Fatalf("failed to select rows for update: %v", err)
+	}
+	if len(names) != 1 || strings.HasPrefix(names[0].Name, "replica") {
+		t.Errorf("row lock expected to be performed on primary, actual: %+v", names)
+	}
+
+	tx, err := a.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if tx.readCon() != tx.con {
+		t.Errorf("transaction expected to read within transaction")
+	}
+
+	atomic.StoreInt32(&db.replicas[0].healthy, 0)
+	for i := 0; i < 3; i++ {
+		if a.readCon() != connection(db.replicas[1].getPool()) {
+			t.Errorf("reads expected to be routed to healthy replica only")
+		}
+	}
+	atomic.StoreInt32(&db.replicas[1].healthy, 0)
+	if a.readCon() != connection(db.pool) {
+		t.Errorf("reads expected to fall back to primary")
+	}
+}
+
+func TestUnreachableReplica(t *testing.T) {
+	var dbName string
+	if err := getConn().QueryRow("SELECT current_database()").Scan(&dbName); err != nil {
+		t.Fatalf("cannot get current database: %v", err)
+	}
+	dsn := testConnString(dbName)
+	db, err := NewDB(dsn, &Config{
+		ReadReplicas:         []string{dsn + " port=1 connect_timeout=1"},
+		ReplicaCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unreachable replica shouldn't prevent db creation: %v", err)
+	}
+	defer db.Close()
+
+	if db.NewAdapter().readCon() != connection(db.pool) {
+		t.Errorf("reads expected to fall back to primary")
+	}
+	if stats := db.Stats(); len(stats.Replicas) != 1 || stats.Replicas[0].MaxConnections != 0 {
+		t.Errorf("empty stats of unreachable replica expected: %+v", stats.Replicas)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	if err := Ping(context.Background()); err != nil {
+		t.Fatalf("unexpected ping error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Ping(ctx); err == nil {
+		t.Errorf("ping error expected for cancelled context")
+	}
+
+	before := Stats()
+	if _, err := getDefaultDB().pool.ExecEx(context.Background(), "SELECT 1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx, err := Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stats := Stats()
+	if stats.MaxConnections != DB_MAX_CONNECTIONS || stats.TotalConnections == 0 {
+		t.Errorf("unexpected pool stats: %+v", stats)
+	}
+	if stats.AcquireCount < before.AcquireCount+2 {
+		t.Errorf("connection acquisitions of exec and transaction expected to be counted")
+	}
+	if stats.InUseConnections != before.InUseConnections {
+		t.Errorf("transaction connection expected to be released, in use: (%d), before: (%d)", stats.InUseConnections, before.InUseConnections)
+	}
+	if stats.InUseConnections+stats.IdleConnections != stats.TotalConnections {
+		t.Errorf("in use and idle connections expected to sum up to total: %+v", stats)
+	}
+
+	rec := httptest.NewRecorder()
+	HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status ok expected, actual: (%d), body: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Status string
+		Stats  PoolStats
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode health response: %v", err)
+	}
+	if resp.Status != "ok" || resp.Stats.MaxConnections != DB_MAX_CONNECTIONS {
+		t.Errorf("unexpected health response: %+v", resp)
+	}
+}
+
+func TestConnMaxLifetime(t *testing.T) {
+	var dbName string
+	if err := getConn().QueryRow("SELECT current_database()").Scan(&dbName); err != nil {
+		t.Fatalf("cannot get current database: %v", err)
+	}
+	db, err := NewDB(testConnString(dbName), &Config{ConnMaxLifetime: time.Hour})
+	if err != nil {
+		t.Fatalf("cannot create db: %v", err)
+	}
+	defer db.Close()
+
+	con, err := db.pool.acquire()
+	if err != nil {
+		t.Fatalf("cannot acquire connection: %v", err)
+	}
+	pid := con.PID()
+	db.pool.Release(con)
+	if con, err = db.pool.acquire(); err != nil {
+		t.Fatalf("cannot acquire connection: %v", err)
+	}
+	if con.PID() != pid {
+		t.Errorf("connection not exceeding max lifetime expected to be reused")
+	}
+
+	expire := func(con *pgx.Conn) {
+		db.pool.mu.Lock()
+		db.pool.created[con] = time.Now().Add(-2 * time.Hour)
+		db.pool.mu.Unlock()
+	}
+	expire(con)
+	db.pool.Release(con)
+	if con.IsAlive() {
+		t.Errorf("expired connection expected to be closed on release")
+	}
+
+	// connection released by pgx pool itself is replaced once acquired
+	if con, err = db.pool.acquire(); err != nil {
+		t.Fatalf("pool expected to open new connection: %v", err)
+	}
+	db.pool.ConnPool.Release(con)
+	expire(con)
+	newCon, err := db.pool.acquire()
+	if err != nil {
+		t.Fatalf("pool expected to open new connection: %v", err)
+	}
+	defer db.pool.Release(newCon)
+	if con.IsAlive() || newCon == con {
+		t.Errorf("expired idle connection expected to be replaced")
+	}
+	if len(db.pool.created) != 1 {
+		t.Errorf("closed connections expected to be forgotten, tracked: (%d)", len(db.pool.created))
+	}
+}
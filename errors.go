@@ -0,0 +1,135 @@
+package pgc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx"
+)
+
+// Postgres error codes (SQLSTATE), see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	PGECTableDNE             = "42P01"
+	PGECTableExists          = "42P07"
+	PGECUniqueViolation      = "23505"
+	PGECForeignKeyViolation  = "23503"
+	PGECNotNullViolation     = "23502"
+	PGECCheckViolation       = "23514"
+	PGECSerializationFailure = "40001"
+	PGECDeadlockDetected     = "40P01"
+)
+
+// Error is an error reported by postgres during pgc operation. It keeps SQLSTATE code
+// and error details, so the original pgx.PgError may be retrieved with errors.As.
+type Error struct {
+	// Op is a pgc operation failed, e.g. "insert" or "update".
+	Op string
+
+	Code       string
+	Message    string
+	Detail     string
+	Hint       string
+	Schema     string
+	Table      string
+	Column     string
+	Constraint string
+
+	pgErr pgx.PgError
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Op, e.pgErr)
+}
+
+// Unwrap returns underlying pgx.PgError.
+func (e *Error) Unwrap() error {
+	return e.pgErr
+}
+
+// Is reports whether target is *Error with the same SQLSTATE code,
+// so errors.Is(err, &pgc.Error{Code: pgc.PGECUniqueViolation}) works as expected.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// wrapError wraps an error of pgc operation op, keeping postgres error details if any.
+func wrapError(op string, err error) error {
+	var pgErr pgx.PgError
+	if !errors.As(err, &pgErr) {
+		return fmt.Errorf("%s error: %w", op, err)
+	}
+
+	return &Error{
+		Op:         op,
+		Code:       pgErr.Code,
+		Message:    pgErr.Message,
+		Detail:     pgErr.Detail,
+		Hint:       pgErr.Hint,
+		Schema:     pgErr.SchemaName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Constraint: pgErr.ConstraintName,
+		pgErr:      pgErr,
+	}
+}
+
+// ErrorCode returns SQLSTATE code of postgres error, or empty string if err isn't reported by postgres.
+func ErrorCode(err error) string {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
+
+// hasErrorCode checks whether err is postgres error with specified SQLSTATE code.
+// Errors, which lost their type by formatting (e.g. with %v verb), are checked by message.
+func hasErrorCode(err error, code string) bool {
+	if err == nil {
+		return false
+	}
+	if errCode := ErrorCode(err); errCode != "" {
+		return errCode == code
+	}
+
+	return strings.Contains(err.Error(), "SQLSTATE "+code)
+}
+
+// IsUniqueViolationError checks whether an error is unique constraint violation error.
+func IsUniqueViolationError(err error) bool {
+	return hasErrorCode(err, PGECUniqueViolation)
+}
+
+// IsTableExistsError checks whether an error is table already exists error.
+func IsTableExistsError(err error) bool {
+	return hasErrorCode(err, PGECTableExists)
+}
+
+// IsForeignKeyViolationError checks whether an error is foreign key constraint violation error.
+func IsForeignKeyViolationError(err error) bool {
+	return hasErrorCode(err, PGECForeignKeyViolation)
+}
+
+// IsNotNullViolationError checks whether an error is not null constraint violation error.
+func IsNotNullViolationError(err error) bool {
+	return hasErrorCode(err, PGECNotNullViolation)
+}
+
+// IsCheckViolationError checks whether an error is check constraint violation error.
+func IsCheckViolationError(err error) bool {
+	return hasErrorCode(err, PGECCheckViolation)
+}
+
+// IsSerializationFailureError checks whether an error is serialization failure error,
+// meaning transaction may be retried.
+func IsSerializationFailureError(err error) bool {
+	return hasErrorCode(err, PGECSerializationFailure)
+}
+
+// IsDeadlockError checks whether an error is deadlock detected error.
+func IsDeadlockError(err error) bool {
+	return hasErrorCode(err, PGECDeadlockDetected)
+}
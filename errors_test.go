@@ -0,0 +1,52 @@
+package pgc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx"
+)
+
+func TestWrapError(t *testing.T) {
+	pgErr := pgx.PgError{
+		Severity:       "ERROR",
+		Code:           PGECForeignKeyViolation,
+		Message:        "insert or update on table \"post\" violates foreign key constraint \"post_user_id_fkey\"",
+		TableName:      "post",
+		ConstraintName: "post_user_id_fkey",
+	}
+	err := wrapError("insert", pgErr)
+
+	var pgcErr *Error
+	if !errors.As(err, &pgcErr) {
+		t.Fatalf("pgc error expected, actual: %T", err)
+	}
+	if pgcErr.Op != "insert" || pgcErr.Table != "post" || pgcErr.Constraint != "post_user_id_fkey" {
+		t.Errorf("error details weren't preserved: %+v", pgcErr)
+	}
+	var origErr pgx.PgError
+	if !errors.As(err, &origErr) || origErr.Code != PGECForeignKeyViolation {
+		t.Errorf("underlying pgx error expected, actual: %v", origErr)
+	}
+	if !errors.Is(err, &Error{Code: PGECForeignKeyViolation}) {
+		t.Errorf("error expected to match by code")
+	}
+	if errors.Is(err, &Error{Code: PGECUniqueViolation}) {
+		t.Errorf("error expected not to match different code")
+	}
+	if !IsForeignKeyViolationError(fmt.Errorf("create post: %w", err)) {
+		t.Errorf("wrapped error expected to be foreign key violation")
+	}
+	if IsUniqueViolationError(err) || IsNotNullViolationError(err) || IsCheckViolationError(err) ||
+		IsSerializationFailureError(err) || IsDeadlockError(err) {
+		t.Errorf("error expected to be foreign key violation only")
+	}
+
+	if err := wrapError("update", pgx.ErrNoRows); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("non postgres error expected to be wrapped, actual: %v", err)
+	}
+	if !IsTableExistsError(errors.New("ERROR: relation \"user\" already exists (SQLSTATE 42P07)")) {
+		t.Errorf("formatted error expected to be checked by message")
+	}
+}
@@ -45,21 +45,47 @@ func renderTemplate(mod interface{}, sqlTemplate string) string {
 	return buff.String()
 }
 
-const insertTemplate = `
+// insertValuesTemplate renders insert statement without trailing semicolon,
+// so it can be extended by other clauses (like ON CONFLICT).
+const insertValuesTemplate = `
 INSERT INTO "{{.model.TableName}}" (
-	{{ range $i, $e := .model.Fields }}
-	{{- if eq $i (minus (len $.model.Fields) 1) }}{{$e.PGNameQuoted}}
+	{{ range $i, $e := .fields }}
+	{{- if eq $i (minus (len $.fields) 1) }}{{$e.PGNameQuoted}}
 	{{- else -}} {{$e.PGNameQuoted}},
 	{{end -}}
 {{- end }}
 ) VALUES 
 	{{- range $itemNum, $item := .Items }}(
-		{{ range $i, $e := $item.Fields -}}
-		${{plus $i 1 (mul (len $.model.Fields) $itemNum)}}{{- if ne $i (minus (len $.model.Fields) 1) }},{{end -}}
+		{{ range $i, $e := $.fields -}}
+		${{plus $i 1 (mul (len $.fields) $itemNum)}}{{- if ne $i (minus (len $.fields) 1) }},{{end -}}
 		{{end }}
 	){{- if ne $itemNum (minus (len $.Items) 1) }},{{- end }}
 	{{end -}}
-;
+`
+
+const insertTemplate = insertValuesTemplate + `;
+`
+
+const insertReturningTemplate = insertValuesTemplate + returningTemplate + `;
+`
+
+const returningTemplate = ` RETURNING {{ range $i, $e := .returning }}{{if $i}}, {{end}}{{$e.PGNameQuoted}}{{end}}`
+
+const onConflictTemplate = `ON CONFLICT 
+	{{- if .constraint }} ON CONSTRAINT "{{.constraint}}"
+	{{- else }} (
+		{{- range $i, $e := .conflictFields }}{{if $i}}, {{end}}{{$e.PGNameQuoted}}{{end -}}
+	)
+	{{- end }}
+	{{- if or .updateFields .version }} DO UPDATE SET
+	{{ range $i, $e := .updateFields }}{{if $i}},
+	{{end}}{{$e.PGNameQuoted}} = EXCLUDED.{{$e.PGNameQuoted}}{{end}}
+	{{- if .version }}{{ if .updateFields }},{{ end }}
+	{{.version.PGNameQuoted}} = "{{.model.TableName}}".{{.version.PGNameQuoted}} + 1
+	{{- end }}
+	{{- else }} DO NOTHING{{ end }}`
+
+const upsertTemplate = insertValuesTemplate + onConflictTemplate + `;
 `
 const selectBaseTemplate = `SELECT
 	{{ range $i, $e := .fields }}
@@ -90,6 +116,9 @@ UPDATE "{{.mod.TableName}}" SET
 	{{- else -}}{{$e.PGNameQuoted}} = ${{plus $i 1}},
 	{{end -}}
 {{- end }}
+{{- if .version }}{{ if .fields }},{{ end }}
+	{{.version.PGNameQuoted}} = {{.version.PGNameQuoted}} + 1
+{{- end }}
 `
 
 const deleteTemplate = `
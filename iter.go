@@ -0,0 +1,128 @@
+package pgc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cliqueinc/pgc/pgcq"
+	"github.com/jackc/pgx"
+)
+
+// Iter is a cursor over select result. Unlike Select, rows are fetched from db one by one
+// while iterating, so memory usage doesn't depend on the number of selected rows.
+// Iter must be closed after usage, otherwise db connection won't be released.
+type Iter struct {
+	rows    *pgx.Rows
+	release func()
+	mod     *model
+	fields  []*field
+	err     error
+}
+
+// MustSelectIter ensures select iterator is created without errors, panics othervise.
+func (a *mustAdapter) MustSelectIter(model interface{}, opts ...pgcq.Option) *Iter {
+	it, err := a.SelectIter(model, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return it
+}
+
+// SelectIter performs select using query options and returns iterator over selected rows.
+// model param is a struct pointer of selected rows type. Joins aren't supported.
+// Keep in mind that the default select limit is applied, use pgcq.All() to iterate over all rows.
+func (a *crudAdapter) SelectIter(model interface{}, opts ...pgcq.Option) (*Iter, error) {
+	stmt, err := pgcq.Build(opts, pgcq.OpSelect)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmt.Joins) != 0 {
+		return nil, errors.New("joins are not supported by select iterator")
+	}
+
+	mod := parseModel(model, false)
+	fields := mod.getFields(stmt.Columns)
+	finalSQL := renderTemplate(Map{"mod": mod, "fields": fields}, selectBaseTemplate) + " " + stmt.Query + ";"
+	if a.db.config.LogQueries {
+		fmt.Println(finalSQL, stmt.Args)
+	}
+
+	a = a.lockingFor(stmt)
+	var (
+		rows    *pgx.Rows
+		release func()
+	)
+	err = a.retryRead(func() error {
+		con, rel, err := acquireConn(a.readCon())
+		if err != nil {
+			return err
+		}
+		if rows, err = con.QueryEx(a.getContext(), finalSQL, nil, stmt.Args...); err != nil {
+			rel()
+			return err
+		}
+		release = rel
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Iter{rows: rows, release: release, mod: mod, fields: fields}, nil
+}
+
+// Next prepares the next row for scanning, returns false if there are no more rows or an error occurred.
+// Rows are closed and db connection is released automatically once all of them are read.
+func (it *Iter) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if !it.rows.Next() {
+		it.Close()
+		return false
+	}
+
+	return true
+}
+
+// Scan scans current row into structPtr, which is expected to be of the same type as model passed to SelectIter.
+func (it *Iter) Scan(structPtr interface{}) error {
+	if reflect.TypeOf(structPtr) != it.mod.ReflectType {
+		return fmt.Errorf("cannot scan (%s) row into (%T)", it.mod.ReflectType, structPtr)
+	}
+
+	rowModel := reflect.ValueOf(structPtr).Elem()
+	valAddrs := make([]interface{}, 0, len(it.fields))
+	for _, f := range it.fields {
+		valAddrs = append(valAddrs, rowModel.Field(f.FieldPos).Addr().Interface())
+	}
+	if err := it.rows.Scan(valAddrs...); err != nil {
+		it.err = err
+		it.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Err returns an error occurred during iteration, if any.
+func (it *Iter) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.rows.Err()
+}
+
+// Close closes iterator and releases db connection. It is safe to call Close multiple times.
+func (it *Iter) Close() {
+	it.rows.Close()
+	if it.release != nil {
+		it.release()
+		it.release = nil
+	}
+}
@@ -0,0 +1,137 @@
+package pgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx"
+)
+
+// ErrLockNotHeld is returned on unlock of advisory lock, which isn't held by the session anymore.
+var ErrLockNotHeld = errors.New("advisory lock is not held")
+
+// LockKey returns advisory lock key of a string name, e.g. "cron:cleanup".
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock is a session level advisory lock. Session lock is held by a dedicated pool connection,
+// which is returned to pool once the lock is released with Unlock.
+type AdvisoryLock struct {
+	key  int64
+	con  *pgx.Conn
+	pool *statPool
+	db   *DB
+}
+
+// Key returns lock key.
+func (l *AdvisoryLock) Key() int64 {
+	return l.key
+}
+
+// Unlock releases the lock and returns its connection to pool. Unlock ignores adapter context,
+// so the lock is not left held in case the context is already done. In case unlock fails,
+// the connection is closed instead of being returned to pool.
+func (l *AdvisoryLock) Unlock() error {
+	if l.con == nil {
+		return ErrLockNotHeld
+	}
+	con := l.con
+	l.con = nil
+	defer l.pool.Release(con)
+
+	var unlocked bool
+	err := queryLock(context.Background(), l.db, con, "SELECT pg_advisory_unlock($1)", l.key, &unlocked)
+	if err == nil && !unlocked {
+		err = ErrLockNotHeld
+	}
+	if err != nil {
+		// closed connection is removed from pool, so the session and its locks are not reused
+		con.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Lock acquires session level advisory lock, waiting until it's available.
+// The lock is held until Unlock is called.
+func (a *Adapter) Lock(key int64) (*AdvisoryLock, error) {
+	l, _, err := a.lock(key, "SELECT true FROM pg_advisory_lock($1)")
+	return l, err
+}
+
+// TryLock tries to acquire session level advisory lock without waiting, and reports whether it succeeded.
+// In case the lock was acquired, it's held until Unlock is called.
+func (a *Adapter) TryLock(key int64) (*AdvisoryLock, bool, error) {
+	return a.lock(key, "SELECT pg_try_advisory_lock($1)")
+}
+
+// lock performs lock sql on dedicated connection, which is kept in case lock was acquired.
+func (a *Adapter) lock(key int64, sql string) (*AdvisoryLock, bool, error) {
+	con, err := a.db.pool.acquire()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	if err := queryLock(a.getContext(), a.db, con, sql, key, &locked); err != nil {
+		a.db.pool.Release(con)
+		return nil, false, err
+	}
+	if !locked {
+		a.db.pool.Release(con)
+		return nil, false, nil
+	}
+
+	return &AdvisoryLock{key: key, con: con, pool: a.db.pool, db: a.db}, true, nil
+}
+
+// WithLock runs fn holding session level advisory lock, bound to ctx. Waits until the lock is available,
+// the lock is released once fn returns. Unlock error is returned in case fn succeeded.
+func (a *Adapter) WithLock(ctx context.Context, key int64, fn func() error) (err error) {
+	l, err := a.WithContext(ctx).Lock(key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := l.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn()
+}
+
+// Lock acquires transaction level advisory lock, waiting until it's available.
+// The lock is released at the end of transaction and cannot be unlocked explicitly.
+func (a *TxAdapter) Lock(key int64) error {
+	var locked bool
+	return queryLock(a.getContext(), a.db, a.con, "SELECT true FROM pg_advisory_xact_lock($1)", key, &locked)
+}
+
+// TryLock tries to acquire transaction level advisory lock without waiting, and reports whether it succeeded.
+// The lock is released at the end of transaction.
+func (a *TxAdapter) TryLock(key int64) (bool, error) {
+	var locked bool
+	err := queryLock(a.getContext(), a.db, a.con, "SELECT pg_try_advisory_xact_lock($1)", key, &locked)
+
+	return locked, err
+}
+
+// queryLock performs advisory lock function call on connection, scanning its result.
+func queryLock(ctx context.Context, db *DB, con connection, sql string, key int64, result *bool) error {
+	if db.config.LogQueries {
+		fmt.Println(sql, key)
+	}
+	if err := con.QueryRowEx(ctx, sql, nil, key).Scan(result); err != nil {
+		return wrapError("advisory lock", err)
+	}
+
+	return nil
+}
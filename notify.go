@@ -0,0 +1,177 @@
+package pgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// subscribeRetryPolicy describes delays between attempts to re-establish subscription connection.
+var subscribeRetryPolicy = RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second, Jitter: 0.2}
+
+// Notification is a notification received from a channel.
+type Notification struct {
+	Channel string
+	Payload string
+	// PID is a process id of the notifying session.
+	PID uint32
+}
+
+// Subscription delivers notifications of subscribed channels.
+type Subscription struct {
+	notifications chan Notification
+
+	mu  sync.Mutex
+	err error
+}
+
+// Notifications returns a channel of received notifications, which is closed once subscription context is done.
+func (s *Subscription) Notifications() <-chan Notification {
+	return s.notifications
+}
+
+// Err returns the latest connection error, or nil if subscription is listening at the moment.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *Subscription) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// MustNotify ensures notification is sent, panics othervise.
+func (a *mustAdapter) MustNotify(channel, payload string) {
+	if err := a.Notify(channel, payload); err != nil {
+		panic(err)
+	}
+}
+
+// Notify sends notification with payload to channel listeners. Notification sent within transaction
+// is delivered once the transaction is committed, and discarded in case of rollback.
+func (a *crudAdapter) Notify(channel, payload string) error {
+	notifySQL := "SELECT pg_notify($1, $2)"
+	if a.db.config.LogQueries {
+		fmt.Println(notifySQL, channel, payload)
+	}
+	if _, err := a.con.ExecEx(a.getContext(), notifySQL, nil, channel, payload); err != nil {
+		return wrapError("notify", err)
+	}
+
+	return nil
+}
+
+// WithConn runs fn on a dedicated pool connection, e.g. in order to use session level features like LISTEN.
+// The connection is returned to pool once fn returns: listened channels are unlistened,
+// and transaction left open is rolled back.
+func (a *Adapter) WithConn(fn func(con *pgx.Conn) error) error {
+	con, err := a.db.pool.acquire()
+	if err != nil {
+		return err
+	}
+	defer a.db.pool.Release(con)
+
+	return fn(con)
+}
+
+// Subscribe listens channels on a dedicated pool connection until ctx is done, delivering notifications
+// on subscription channel. In case the connection fails, it's re-established and channels are listened again,
+// keep in mind that notifications sent while reconnecting are lost.
+// Error is returned in case channels cannot be listened initially, or ctx is done before that.
+func (a *Adapter) Subscribe(ctx context.Context, channels ...string) (*Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("channels for subscribe cannot be empty")
+	}
+	con, err := listen(ctx, a.db.pool, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{notifications: make(chan Notification, 64)}
+	go s.run(ctx, a.db.pool, channels, con)
+
+	return s, nil
+}
+
+// run receives notifications, re-establishing connection on failure, until ctx is done.
+func (s *Subscription) run(ctx context.Context, pool *statPool, channels []string, con *pgx.Conn) {
+	defer close(s.notifications)
+	for attempt := 0; ; {
+		if con != nil {
+			err := s.receive(ctx, con)
+			releaseListener(pool, con)
+			con = nil
+			if ctx.Err() != nil {
+				return
+			}
+			s.setErr(err)
+		}
+
+		attempt++
+		timer := time.NewTimer(subscribeRetryPolicy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		var err error
+		if con, err = listen(ctx, pool, channels); err != nil {
+			s.setErr(err)
+			continue
+		}
+		attempt = 0
+		s.setErr(nil)
+	}
+}
+
+// receive passes notifications received by connection to subscription channel, until connection fails or ctx is done.
+func (s *Subscription) receive(ctx context.Context, con *pgx.Conn) error {
+	for {
+		n, err := con.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case s.notifications <- Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// listen acquires pool connection and listens channels on it, bound to ctx.
+func listen(ctx context.Context, pool *statPool, channels []string) (*pgx.Conn, error) {
+	con, err := pool.acquireContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		if _, err := con.ExecEx(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize(), nil); err != nil {
+			releaseListener(pool, con)
+			return nil, wrapError("listen", err)
+		}
+	}
+
+	return con, nil
+}
+
+// releaseListener unlistens all channels of connection and returns it to pool. Pool unlistens only channels
+// listened with Conn.Listen, which doesn't support context, so it's done explicitly.
+func releaseListener(pool *statPool, con *pgx.Conn) {
+	if con.IsAlive() {
+		if _, err := con.Exec("UNLISTEN *"); err != nil {
+			con.Close()
+		}
+	}
+	pool.Release(con)
+}
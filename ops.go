@@ -1,6 +1,7 @@
 package pgc
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -20,9 +21,26 @@ func getDefault() *Adapter {
 	return defaultAdapter
 }
 
-// NewAdapter creates new adapter instance.
+// NewAdapter creates new adapter instance of default connection pool.
 func NewAdapter() *Adapter {
-	return &Adapter{&mustAdapter{&crudAdapter{con: getConn()}}}
+	return getDefaultDB().NewAdapter()
+}
+
+// WithContext returns default adapter bound to ctx, so its operations are cancelled
+// once ctx is done or its deadline is exceeded.
+func WithContext(ctx context.Context) *Adapter {
+	return getDefault().WithContext(ctx)
+}
+
+// IgnoreNotFound returns default adapter, which doesn't return ErrNotFound
+// when update or delete by primary key doesn't affect any row.
+func IgnoreNotFound() *Adapter {
+	return getDefault().IgnoreNotFound()
+}
+
+// Primary returns default adapter, which always reads from primary db instead of read replicas.
+func Primary() *Adapter {
+	return getDefault().Primary()
 }
 
 // Begin begins new transaction.
@@ -30,6 +48,42 @@ func Begin() (*TxAdapter, error) {
 	return getDefault().Begin()
 }
 
+// BeginTx begins new transaction with options.
+func BeginTx(opts *TxOptions) (*TxAdapter, error) {
+	return getDefault().BeginTx(opts)
+}
+
+// RunInTx runs fn within transaction, committing it if fn returns nil, and rolling back otherwise.
+// Transaction failed with serialization failure or deadlock is retried, see Adapter.RunInTx.
+func RunInTx(ctx context.Context, opts *TxOptions, fn func(tx *TxAdapter) error) error {
+	return getDefault().RunInTx(ctx, opts, fn)
+}
+
+// Lock acquires session level advisory lock, see Adapter.Lock.
+func Lock(key int64) (*AdvisoryLock, error) {
+	return getDefault().Lock(key)
+}
+
+// TryLock tries to acquire session level advisory lock without waiting, see Adapter.TryLock.
+func TryLock(key int64) (*AdvisoryLock, bool, error) {
+	return getDefault().TryLock(key)
+}
+
+// WithLock runs fn holding session level advisory lock, see Adapter.WithLock.
+func WithLock(ctx context.Context, key int64, fn func() error) error {
+	return getDefault().WithLock(ctx, key, fn)
+}
+
+// Notify sends notification with payload to channel listeners.
+func Notify(channel, payload string) error {
+	return getDefault().Notify(channel, payload)
+}
+
+// Subscribe listens channels until ctx is done, see Adapter.Subscribe.
+func Subscribe(ctx context.Context, channels ...string) (*Subscription, error) {
+	return getDefault().Subscribe(ctx, channels...)
+}
+
 // MustInsert ensures struct will be inserted without errors, panics othervise.
 // Limit of items to insert at once is 1000 items.
 func MustInsert(structPtrs ...interface{}) {
@@ -42,6 +96,41 @@ func Insert(structPtrs ...interface{}) error {
 	return getDefault().Insert(structPtrs...)
 }
 
+// MustInsertReturning ensures structs are inserted without errors, panics othervise.
+// Limit of items to insert at once is 1000 items.
+func MustInsertReturning(returning Returning, structPtrs ...interface{}) {
+	getDefault().MustInsertReturning(returning, structPtrs...)
+}
+
+// InsertReturning inserts structs into db and scans values of returned columns back into structs,
+// columns generated by db are omitted from insert, see Returning. Limit of items to insert at once is 1000 items.
+func InsertReturning(returning Returning, structPtrs ...interface{}) error {
+	return getDefault().InsertReturning(returning, structPtrs...)
+}
+
+// MustBulkInsert ensures structs are bulk inserted without errors, panics othervise. Returns number of inserted rows.
+func MustBulkInsert(src interface{}) int64 {
+	return getDefault().MustBulkInsert(src)
+}
+
+// BulkInsert inserts large amount of structs using postgres copy protocol, returns number of inserted rows.
+// src is expected to be a slice of structs (or struct pointers), or a channel of them.
+func BulkInsert(src interface{}) (int64, error) {
+	return getDefault().BulkInsert(src)
+}
+
+// MustUpsert ensures structs are upserted without errors, panics othervise.
+// Limit of items to upsert at once is 1000 items.
+func MustUpsert(conflict Conflict, structPtrs ...interface{}) {
+	getDefault().MustUpsert(conflict, structPtrs...)
+}
+
+// Upsert inserts structs into db, resolving conflicts as described by conflict param.
+// Limit of items to upsert at once is 1000 items.
+func Upsert(conflict Conflict, structPtrs ...interface{}) error {
+	return getDefault().Upsert(conflict, structPtrs...)
+}
+
 // MustUpdate ensures struct will be updated without errors, panics othervise.
 func MustUpdate(structPtr interface{}) {
 	getDefault().MustUpdate(structPtr)
@@ -52,6 +141,27 @@ func Update(structPtr interface{}) error {
 	return getDefault().Update(structPtr)
 }
 
+// MustUpdateColumns ensures struct columns will be updated without errors, panics othervise.
+func MustUpdateColumns(structPtr interface{}, columns ...string) {
+	getDefault().MustUpdateColumns(structPtr, columns...)
+}
+
+// UpdateColumns updates only specified columns of struct by primary key.
+func UpdateColumns(structPtr interface{}, columns ...string) error {
+	return getDefault().UpdateColumns(structPtr, columns...)
+}
+
+// MustUpdateReturning ensures struct will be updated without errors, panics othervise.
+func MustUpdateReturning(structPtr interface{}, columns ...string) {
+	getDefault().MustUpdateReturning(structPtr, columns...)
+}
+
+// UpdateReturning updates struct by primary key and scans values of given columns back into struct.
+// If no columns specified, all columns returned.
+func UpdateReturning(structPtr interface{}, columns ...string) error {
+	return getDefault().UpdateReturning(structPtr, columns...)
+}
+
 // MustUpdateRows ensures rows are updated without errors, panics othervise. Returns number of affected rows.
 // In case when you really need to update all rows (e.g. migration script), you need to pass pgc.QueryAll() option.
 // It is done to avoid unintentional update of all rows.
@@ -77,6 +187,29 @@ func Select(destSlicePtr interface{}, opts ...pgcq.Option) error {
 	return getDefault().Select(destSlicePtr, opts...)
 }
 
+// MustSelectPage ensures page select will not produce any error, panics othervise. Returns the next page cursor.
+func MustSelectPage(destSlicePtr interface{}, opts ...pgcq.Option) pgcq.Cursor {
+	return getDefault().MustSelectPage(destSlicePtr, opts...)
+}
+
+// SelectPage performs select with keyset pagination (see pgcq.Seek), returns the cursor of the next page.
+// Empty next cursor means there are no more pages.
+// destSlicePtr parameter expects pointer to a slice
+func SelectPage(destSlicePtr interface{}, opts ...pgcq.Option) (next pgcq.Cursor, err error) {
+	return getDefault().SelectPage(destSlicePtr, opts...)
+}
+
+// MustSelectIter ensures select iterator is created without errors, panics othervise.
+func MustSelectIter(model interface{}, opts ...pgcq.Option) *Iter {
+	return getDefault().MustSelectIter(model, opts...)
+}
+
+// SelectIter performs select using query options and returns iterator over selected rows.
+// model param is a struct pointer of selected rows type.
+func SelectIter(model interface{}, opts ...pgcq.Option) (*Iter, error) {
+	return getDefault().SelectIter(model, opts...)
+}
+
 // MustSelectCustomData ensures select will not produce any error, panics othervise.
 func MustSelectCustomData(model interface{}, destSlicePtr interface{}, opts ...pgcq.Option) {
 	getDefault().MustSelectCustomData(model, destSlicePtr, opts...)
@@ -141,24 +274,6 @@ func CreateTable(structPtr interface{}) error {
 	return a.CreateTable(structPtr)
 }
 
-// IsUniqueViolationError checks whether an error is unique constraint violation error.
-func IsUniqueViolationError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	return strings.Contains(err.Error(), "SQLSTATE "+PGECUniqueViolation)
-}
-
-// IsTableExistsError checks whether an error is table already exists error.
-func IsTableExistsError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	return strings.Contains(err.Error(), PGECTableExists)
-}
-
 // SelectAllWhere performs raw select and panics in case of errors.
 func SelectAllWhere(destSlicePtr interface{}, sqlWhereStmt string, args ...interface{}) {
 	if sqlWhereStmt != "" && strings.HasPrefix(strings.ToLower(sqlWhereStmt), "select") {
@@ -174,7 +289,7 @@ func SelectAllWhere(destSlicePtr interface{}, sqlWhereStmt string, args ...inter
 	if cfg.LogQueries {
 		fmt.Println(finalSQL)
 	}
-	err = rawSelect(finalSQL, nil, nil, nil, true, sliceValElement, sliceTypeElement, getDefault().con, args...)
+	err = rawSelect(finalSQL, nil, nil, nil, true, sliceValElement, sliceTypeElement, getDefault().crudAdapter, args...)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -182,19 +297,37 @@ func SelectAllWhere(destSlicePtr interface{}, sqlWhereStmt string, args ...inter
 
 func Query(stmt string, args ...interface{}) (*pgx.Rows, error) {
 	con := getDefault().con
-	rows, err := con.Query(stmt, args...)
+	rows, err := con.QueryEx(context.Background(), stmt, nil, args...)
 
 	return rows, err
 }
 
 func rawSelect(sqlStmt string, columns []string, joinMods []*model, joinFields [][]*field, requirePK bool, sliceValElement reflect.Value,
-	sliceTypeElement reflect.Type, con connection, args ...interface{}) error {
+	sliceTypeElement reflect.Type, a *crudAdapter, args ...interface{}) error {
 
-	if cfg.LogQueries {
+	if a.db.config.LogQueries {
 		fmt.Println(sqlStmt, args)
 	}
 
-	rows, err := con.Query(sqlStmt, args...)
+	// in case select is retried, rows appended by the failed attempt are dropped
+	sliceLen := sliceValElement.Len()
+	return a.retryRead(func() error {
+		sliceValElement.SetLen(sliceLen)
+		return selectRows(sqlStmt, columns, joinMods, joinFields, requirePK, sliceValElement, sliceTypeElement, a, args...)
+	})
+}
+
+// selectRows performs select query and appends selected rows into slice.
+func selectRows(sqlStmt string, columns []string, joinMods []*model, joinFields [][]*field, requirePK bool, sliceValElement reflect.Value,
+	sliceTypeElement reflect.Type, a *crudAdapter, args ...interface{}) error {
+
+	con, release, err := acquireConn(a.readCon())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := con.QueryEx(a.getContext(), sqlStmt, nil, args...)
 	if err != nil {
 		return err
 	}
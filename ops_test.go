@@ -1,6 +1,8 @@
 package pgc_test
 
 import (
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -109,6 +111,119 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestSelectIter(t *testing.T) {
+	type fakeIter struct {
+		ID   string
+		Name string
+		Num  int
+	}
+	pgc.MustCreateTable(&fakeIter{})
+	items := make([]fakeIter, 0, 20)
+	for i := 0; i < 20; i++ {
+		items = append(items, fakeIter{ID: util.RandomString(25), Name: util.RandomString(10), Num: i})
+	}
+	pgc.MustBulkInsert(items)
+
+	it, err := pgc.SelectIter(&fakeIter{}, pgcq.GreaterOrEqual("num", 5), pgcq.Order("num", pgcq.ASC), pgcq.All())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer it.Close()
+
+	var num int
+	for it.Next() {
+		var f fakeIter
+		if err := it.Scan(&f); err != nil {
+			t.Fatalf("fail scan row: %v", err)
+		}
+		if f != items[num+5] {
+			t.Fatalf("expected row: %+v, actual: %+v", items[num+5], f)
+		}
+		num++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration error: %v", err)
+	}
+	if num != 15 {
+		t.Errorf("expected (%d) rows, actual: (%d)", 15, num)
+	}
+
+	it = pgc.MustSelectIter(&fakeIter{}, pgcq.Limit(1))
+	defer it.Close()
+	for it.Next() {
+		if err := it.Scan(&selectTest{}); err == nil {
+			t.Errorf("error expected for scanning into a struct of different type")
+		}
+	}
+}
+
+func TestSelectPage(t *testing.T) {
+	type fakePage struct {
+		ID      string
+		Name    string
+		Created time.Time
+	}
+	pgc.MustCreateTable(&fakePage{})
+	created := time.Now().UTC().Truncate(time.Microsecond)
+	items := make([]fakePage, 0, 25)
+	for i := 0; i < 25; i++ {
+		// only a few distinct values, so the primary key needs to break ties
+		items = append(items, fakePage{
+			ID:      util.RandomString(25),
+			Name:    "name" + strconv.Itoa(i%3),
+			Created: created.Add(time.Duration(i%4) * time.Hour),
+		})
+	}
+	pgc.MustBulkInsert(items)
+
+	for _, seekColumns := range [][]string{{"name"}, {"created", "name"}} {
+		t.Run(strings.Join(seekColumns, ","), func(t *testing.T) {
+			var (
+				cursor pgcq.Cursor
+				pages  int
+				seen   = make(map[string]struct{})
+			)
+			for {
+				var page []fakePage
+				next, err := pgc.SelectPage(&page, pgcq.Seek(cursor, pgcq.DESC, seekColumns...), pgcq.Limit(10))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				pages++
+				for _, p := range page {
+					if _, ok := seen[p.ID]; ok {
+						t.Fatalf("row (%s) fetched twice", p.ID)
+					}
+					seen[p.ID] = struct{}{}
+				}
+				if next == "" {
+					break
+				}
+				if pages > 3 {
+					t.Fatalf("too many pages")
+				}
+				cursor = next
+			}
+			if len(seen) != len(items) {
+				t.Errorf("expected (%d) rows fetched, actual: (%d)", len(items), len(seen))
+			}
+		})
+	}
+
+	t.Run("seek required", func(t *testing.T) {
+		var page []fakePage
+		if _, err := pgc.SelectPage(&page, pgcq.Limit(10)); err == nil {
+			t.Errorf("error expected if no seek option specified")
+		}
+	})
+	t.Run("invalid cursor", func(t *testing.T) {
+		var page []fakePage
+		if _, err := pgc.SelectPage(&page, pgcq.Seek("invalid", pgcq.ASC, "name")); err == nil {
+			t.Errorf("error expected for invalid cursor")
+		}
+	})
+}
+
 func TestMustSelectPanicNoSlicePtr(t *testing.T) {
 	// Panics because you should be passing pointer to slice not slice
 	var sl []selectTest
@@ -203,6 +318,66 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestRowLock(t *testing.T) {
+	type fakeLock struct {
+		ID     string
+		Status string
+	}
+	f1 := &fakeLock{ID: util.RandomString(25), Status: "pending"}
+	f2 := &fakeLock{ID: util.RandomString(25), Status: "pending"}
+	pgc.MustCreateTable(f1)
+	pgc.MustInsert(f1, f2)
+
+	tx, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	// no where option, so the row is locked by primary key
+	if found, err := tx.Get(&fakeLock{ID: f1.ID}, pgcq.ForUpdate()); err != nil || !found {
+		t.Fatalf("failed to get row for update, found: %v, err: %v", found, err)
+	}
+
+	tx2, err := pgc.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	defer tx2.Rollback()
+	var locked []fakeLock
+	err = tx2.Select(&locked, pgcq.IN("id", f1.ID, f2.ID), pgcq.ForUpdate(pgcq.SkipLocked()))
+	if err != nil {
+		t.Fatalf("failed to select rows for update: %v", err)
+	}
+	if len(locked) != 1 || locked[0].ID != f2.ID {
+		t.Errorf("expected only row (%s) to be selected, got: %+v", f2.ID, locked)
+	}
+
+	_, err = tx2.Get(&fakeLock{ID: f1.ID}, pgcq.ForShare(pgcq.NoWait()))
+	if code := pgc.ErrorCode(err); code != "55P03" {
+		t.Errorf("expected lock not available error, got (%v)", err)
+	}
+
+	if err := pgc.Select(&locked, pgcq.ForUpdate(pgcq.NoWait(), pgcq.SkipLocked())); err == nil {
+		t.Errorf("expected error combining nowait with skip locked")
+	}
+	if _, err := pgc.DeleteRows(&fakeLock{}, pgcq.Equal("id", f1.ID), pgcq.ForUpdate()); err == nil {
+		t.Errorf("expected error using row lock in delete")
+	}
+
+	// order affects selected row, so it isn't got by primary key
+	lastID := f1.ID
+	if f2.ID > lastID {
+		lastID = f2.ID
+	}
+	last := &fakeLock{}
+	if found, err := pgc.Get(last, pgcq.Order("id", pgcq.DESC)); err != nil || !found {
+		t.Fatalf("failed to get ordered row, found: %v, err: %v", found, err)
+	}
+	if last.ID != lastID {
+		t.Errorf("expected row (%s), got (%s)", lastID, last.ID)
+	}
+}
+
 func TestMustInsert(t *testing.T) {
 	type fakeInsert1 struct {
 		ID   string
@@ -264,6 +439,13 @@ func TestInsert(t *testing.T) {
 		t.Errorf("TestInsertWErr InsertErrS expected message for unique violation was (%s), actual: (%v)",
 			pgc.PGECUniqueViolation, err)
 	}
+	var pgcErr *pgc.Error
+	if !errors.As(err, &pgcErr) {
+		t.Fatalf("TestInsertWErr expected pgc error, actual: (%T)", err)
+	}
+	if pgcErr.Code != pgc.PGECUniqueViolation || pgcErr.Constraint != "fake_insert2_pkey" || pgcErr.Table != "fake_insert2" {
+		t.Errorf("TestInsertWErr expected error details to be preserved, actual: (%+v)", pgcErr)
+	}
 
 	t.Run("insert more that limit allows", func(t *testing.T) {
 		items := make([]interface{}, 0, pgc.LimitInsert+1)
@@ -277,6 +459,245 @@ func TestInsert(t *testing.T) {
 	})
 }
 
+func TestBulkInsert(t *testing.T) {
+	type fakeBulk struct {
+		ID      string
+		Name    string
+		Tags    []string
+		Meta    map[string]interface{}
+		Created time.Time
+	}
+	pgc.MustCreateTable(&fakeBulk{})
+
+	t.Run("slice", func(t *testing.T) {
+		items := make([]fakeBulk, 0, pgc.LimitInsert+1)
+		for i := 0; i < pgc.LimitInsert+1; i++ {
+			items = append(items, fakeBulk{
+				ID:      util.RandomString(25),
+				Name:    util.RandomString(10),
+				Tags:    []string{"a", "b"},
+				Meta:    map[string]interface{}{"num": i},
+				Created: time.Now().UTC(),
+			})
+		}
+		num, err := pgc.BulkInsert(items)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if num != int64(len(items)) {
+			t.Fatalf("expected (%d) rows inserted, actual: (%d)", len(items), num)
+		}
+		f := &fakeBulk{ID: items[5].ID}
+		if !pgc.MustGet(f) || f.Name != items[5].Name || len(f.Tags) != 2 {
+			t.Errorf("inserted row doesn't match, expected: %+v, actual: %+v", items[5], f)
+		}
+	})
+	t.Run("channel", func(t *testing.T) {
+		ch := make(chan *fakeBulk)
+		go func() {
+			for i := 0; i < 10; i++ {
+				ch <- &fakeBulk{ID: util.RandomString(25)}
+			}
+			close(ch)
+		}()
+		num := pgc.MustBulkInsert(ch)
+		if num != 10 {
+			t.Fatalf("expected (%d) rows inserted, actual: (%d)", 10, num)
+		}
+	})
+	t.Run("not a slice", func(t *testing.T) {
+		if _, err := pgc.BulkInsert(&fakeBulk{}); err == nil {
+			t.Errorf("error expected for struct source")
+		}
+	})
+}
+
+func TestUpsert(t *testing.T) {
+	type fakeUpsert struct {
+		ID     string
+		Email  string
+		Name   string
+		Scores int
+	}
+	pgc.MustCreateTable(&fakeUpsert{})
+	rows, err := pgc.Query(`CREATE UNIQUE INDEX "fake_upsert_email_idx" ON "fake_upsert" ("email")`)
+	if err != nil {
+		t.Fatalf("fail create unique index: %v", err)
+	}
+	rows.Close()
+
+	f1 := &fakeUpsert{ID: util.RandomString(25), Email: util.RandomString(10), Name: "Bob", Scores: 10}
+	f2 := &fakeUpsert{ID: util.RandomString(25), Email: util.RandomString(10), Name: "John", Scores: 20}
+	pgc.MustUpsert(pgc.Conflict{}, f1, f2)
+
+	t.Run("update by PK", func(t *testing.T) {
+		pgc.MustUpsert(pgc.Conflict{}, &fakeUpsert{ID: f1.ID, Email: f1.Email, Name: "Bob2", Scores: 11})
+		f := &fakeUpsert{ID: f1.ID}
+		pgc.MustGet(f)
+		if f.Name != "Bob2" || f.Scores != 11 {
+			t.Errorf("row wasn't updated on conflict, actual: %+v", f)
+		}
+	})
+	t.Run("do nothing", func(t *testing.T) {
+		err := pgc.Upsert(pgc.Conflict{DoNothing: true}, &fakeUpsert{ID: f2.ID, Email: f2.Email, Name: "John2"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f := &fakeUpsert{ID: f2.ID}
+		pgc.MustGet(f)
+		if f.Name != "John" {
+			t.Errorf("row shouldn't be updated, actual name: (%s)", f.Name)
+		}
+	})
+	t.Run("update selected columns by unique column", func(t *testing.T) {
+		err := pgc.Upsert(
+			pgc.Conflict{Columns: []string{"email"}, Update: []string{"scores"}},
+			&fakeUpsert{ID: util.RandomString(25), Email: f2.Email, Name: "John3", Scores: 30},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f := &fakeUpsert{ID: f2.ID}
+		pgc.MustGet(f)
+		if f.Scores != 30 || f.Name != "John" {
+			t.Errorf("only scores expected to be updated, actual: %+v", f)
+		}
+	})
+	t.Run("update all columns by unique column", func(t *testing.T) {
+		err := pgc.Upsert(
+			pgc.Conflict{Columns: []string{"email"}},
+			&fakeUpsert{ID: util.RandomString(25), Email: f1.Email, Name: "Bob3", Scores: 12},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f := &fakeUpsert{ID: f1.ID}
+		if found := pgc.MustGet(f); !found {
+			t.Fatalf("primary key shouldn't be updated")
+		}
+		if f.Scores != 12 || f.Name != "Bob3" {
+			t.Errorf("row wasn't updated on conflict, actual: %+v", f)
+		}
+	})
+	t.Run("unknown column", func(t *testing.T) {
+		if err := pgc.Upsert(pgc.Conflict{Update: []string{"unknown"}}, f1); err == nil {
+			t.Errorf("error expected for unknown update column")
+		}
+	})
+	if count := pgc.MustCount(&fakeUpsert{}); count != 2 {
+		t.Errorf("expected (%d) rows, actual: (%d)", 2, count)
+	}
+}
+
+func TestUpsertVersion(t *testing.T) {
+	type fakeUpsertVersion struct {
+		ID      string
+		Name    string
+		Version int64 `pgc:"version"`
+	}
+	pgc.MustCreateTable(&fakeUpsertVersion{})
+
+	f := &fakeUpsertVersion{ID: util.RandomString(25), Name: "Bob"}
+	pgc.MustUpsert(pgc.Conflict{}, f)
+	pgc.MustUpsert(pgc.Conflict{}, &fakeUpsertVersion{ID: f.ID, Name: "Bob2", Version: 10})
+
+	stored := &fakeUpsertVersion{ID: f.ID}
+	pgc.MustGet(stored)
+	if stored.Name != "Bob2" || stored.Version != 1 {
+		t.Errorf("row expected to be updated with version incremented, actual: %+v", stored)
+	}
+
+	if err := pgc.Upsert(pgc.Conflict{Update: []string{"version"}}, f); err == nil {
+		t.Errorf("error expected for version update column")
+	}
+}
+
+func TestReturning(t *testing.T) {
+	type fakeReturning struct {
+		ID      string
+		Name    string
+		Seq     int64
+		Created time.Time
+	}
+	pgc.MustCreateTable(&fakeReturning{})
+	for _, sql := range []string{
+		`CREATE SEQUENCE "fake_returning_seq"`,
+		`ALTER TABLE "fake_returning" ALTER COLUMN "seq" SET DEFAULT nextval('fake_returning_seq')`,
+		`ALTER TABLE "fake_returning" ALTER COLUMN "created" SET DEFAULT now()`,
+	} {
+		rows, err := pgc.Query(sql)
+		if err != nil {
+			t.Fatalf("fail prepare table: %v", err)
+		}
+		rows.Close()
+	}
+
+	f1 := &fakeReturning{ID: util.RandomString(25), Name: "Bob"}
+	f2 := &fakeReturning{ID: util.RandomString(25), Name: "John"}
+	t.Run("insert", func(t *testing.T) {
+		if err := pgc.InsertReturning(pgc.Returning{Generated: []string{"seq", "created"}}, f1, f2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f1.Seq != 1 || f2.Seq != 2 {
+			t.Errorf("generated values weren't scanned in order, actual seq: (%d), (%d)", f1.Seq, f2.Seq)
+		}
+		if f1.Created.IsZero() || f2.Created.IsZero() {
+			t.Errorf("default created time wasn't scanned")
+		}
+	})
+	t.Run("insert returned columns", func(t *testing.T) {
+		f := &fakeReturning{ID: util.RandomString(25), Name: "Jim", Seq: 100}
+		if err := pgc.InsertReturning(pgc.Returning{Columns: []string{"seq", "created"}}, f); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Seq != 100 {
+			t.Errorf("returned column expected to be inserted, actual seq: (%d)", f.Seq)
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		f := &fakeReturning{ID: f1.ID, Name: "Bob2"}
+		if err := pgc.UpdateReturning(f); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Name != "Bob2" || f.Seq != 0 {
+			t.Errorf("stored values expected to be scanned, actual: %+v", f)
+		}
+	})
+	t.Run("unknown column", func(t *testing.T) {
+		if err := pgc.InsertReturning(pgc.Returning{Columns: []string{"unknown"}}, &fakeReturning{ID: util.RandomString(25)}); err == nil {
+			t.Errorf("error expected for unknown returning column")
+		}
+		if err := pgc.InsertReturning(pgc.Returning{Generated: []string{"unknown"}}, &fakeReturning{ID: util.RandomString(25)}); err == nil {
+			t.Errorf("error expected for unknown generated column")
+		}
+	})
+	t.Run("rows matched by primary key", func(t *testing.T) {
+		for _, sql := range []string{
+			`CREATE FUNCTION fake_returning_upper() RETURNS trigger AS $$ BEGIN NEW.name = upper(NEW.name); RETURN NEW; END $$ LANGUAGE plpgsql`,
+			`CREATE TRIGGER fake_returning_upper BEFORE INSERT ON "fake_returning" FOR EACH ROW EXECUTE PROCEDURE fake_returning_upper()`,
+		} {
+			rows, err := pgc.Query(sql)
+			if err != nil {
+				t.Fatalf("fail prepare trigger: %v", err)
+			}
+			rows.Close()
+		}
+		items := []interface{}{
+			&fakeReturning{ID: util.RandomString(25), Name: "alice"},
+			&fakeReturning{ID: util.RandomString(25), Name: "jack"},
+			&fakeReturning{ID: util.RandomString(25), Name: "kate"},
+		}
+		if err := pgc.InsertReturning(pgc.Returning{Columns: []string{"name"}}, items...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i, name := range []string{"ALICE", "JACK", "KATE"} {
+			if f := items[i].(*fakeReturning); f.Name != name {
+				t.Errorf("expected name (%s) to be scanned into struct (%s), actual: (%s)", name, f.ID, f.Name)
+			}
+		}
+	})
+}
+
 func TestUpdate(t *testing.T) {
 	type fakeUpdate struct {
 		ID        string
@@ -301,6 +722,42 @@ func TestUpdate(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
+	t.Run("update missing", func(t *testing.T) {
+		missing := &fakeUpdate{ID: util.RandomString(25), Name: "Forest"}
+		if err := pgc.Update(missing); err != pgc.ErrNotFound {
+			t.Errorf("not found error expected, actual: %v", err)
+		}
+		if err := pgc.UpdateReturning(missing); err != pgc.ErrNotFound {
+			t.Errorf("not found error expected, actual: %v", err)
+		}
+		if err := pgc.IgnoreNotFound().Update(missing); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("update columns", func(t *testing.T) {
+		f := &fakeUpdate{ID: f1.ID, Name: "Forest", Scores: 300}
+		if err := pgc.UpdateColumns(f, "scores"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fGet := &fakeUpdate{ID: f1.ID}
+		pgc.MustGet(fGet)
+		if fGet.Scores != 300 {
+			t.Errorf("scores expected to be updated, actual: (%d)", fGet.Scores)
+		}
+		if fGet.Name != f1.Name || fGet.CompanyID != f1.CompanyID {
+			t.Errorf("columns not listed for update shouldn't be changed, actual: %+v", fGet)
+		}
+
+		if err := pgc.UpdateColumns(f, "unknown"); err == nil {
+			t.Errorf("error expected for unknown column")
+		}
+		if err := pgc.UpdateColumns(f, "id"); err == nil {
+			t.Errorf("error expected for primary key column")
+		}
+		if err := pgc.UpdateColumns(f); err == nil {
+			t.Errorf("error expected for empty columns")
+		}
+	})
 	t.Run("update rows by column", func(t *testing.T) {
 		num, err := pgc.UpdateRows(&fakeUpdate{}, pgc.Map{"scores": 50, "is_active": false}, pgcq.Equal("company_id", companyID2))
 		if err != nil {
@@ -346,6 +803,48 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestUpdateVersion(t *testing.T) {
+	type fakeVersion struct {
+		ID      string
+		Name    string
+		Version int64 `pgc:"version"`
+	}
+	pgc.MustCreateTable(&fakeVersion{})
+	f := &fakeVersion{ID: util.RandomString(25), Name: "Bob"}
+	pgc.MustInsert(f)
+
+	f1 := &fakeVersion{ID: f.ID}
+	pgc.MustGet(f1)
+	f2 := &fakeVersion{ID: f.ID}
+	pgc.MustGet(f2)
+
+	f1.Name = "John"
+	if err := pgc.Update(f1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f1.Version != 1 {
+		t.Errorf("struct version expected to be incremented, actual: (%d)", f1.Version)
+	}
+
+	f2.Name = "James"
+	if err := pgc.Update(f2); err != pgc.ErrStaleObject {
+		t.Fatalf("stale object error expected, actual: %v", err)
+	}
+	if err := pgc.UpdateReturning(f2); err != pgc.ErrStaleObject {
+		t.Fatalf("stale object error expected, actual: %v", err)
+	}
+
+	num := pgc.MustUpdateRows(&fakeVersion{}, pgc.Map{"name": "Forest"}, pgcq.Equal("id", f.ID))
+	if num != 1 {
+		t.Fatalf("1 row expected to be updated, actual: (%d)", num)
+	}
+	fGet := &fakeVersion{ID: f.ID}
+	pgc.MustGet(fGet)
+	if fGet.Name != "Forest" || fGet.Version != 2 {
+		t.Errorf("row version expected to be incremented by update rows, actual: %+v", fGet)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	type fakeDelete struct {
 		ID        string
@@ -372,6 +871,14 @@ func TestDelete(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
+	t.Run("delete missing", func(t *testing.T) {
+		if err := pgc.Delete(f4); err != pgc.ErrNotFound {
+			t.Errorf("not found error expected, actual: %v", err)
+		}
+		if err := pgc.IgnoreNotFound().Delete(f4); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
 	t.Run("delete rows", func(t *testing.T) {
 		num, err := pgc.DeleteRows(&fakeDelete{}, pgcq.Equal("company_id", companyID2))
 		if err != nil {
@@ -724,6 +1231,64 @@ func TestSelectWithOpts(t *testing.T) {
 	})
 }
 
+func TestSelectIN(t *testing.T) {
+	type fakeINItem struct {
+		ID  string
+		Num int64
+	}
+	items := []*fakeINItem{
+		{ID: util.RandomString(25), Num: 1},
+		{ID: util.RandomString(25), Num: 2},
+		{ID: util.RandomString(25), Num: 3},
+	}
+	pgc.MustCreateTable(&fakeINItem{})
+	for _, item := range items {
+		pgc.MustInsert(item)
+	}
+	ids := []string{items[0].ID, items[1].ID, items[2].ID}
+
+	tests := []struct {
+		name     string
+		opt      pgcq.Option
+		expected []int64
+	}{
+		{"int values", pgcq.IN("num", 1, 3), []int64{1, 3}},
+		{"slice", pgcq.IN("num", []int64{2, 3}), []int64{2, 3}},
+		{"not in", pgcq.NotIN("num", []int64{1, 3}), []int64{2}},
+		{"any", pgcq.Any("num", []int64{1, 2}), []int64{1, 2}},
+		{"any empty", pgcq.Any("num", []int64{}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched []fakeINItem
+			err := pgc.Select(&fetched, pgcq.Any("id", ids), tt.opt, pgcq.Order("num", pgcq.ASC))
+			if err != nil {
+				t.Fatalf("failed to select: %v", err)
+			}
+			var nums []int64
+			for _, item := range fetched {
+				nums = append(nums, item.Num)
+			}
+			if len(nums) != len(tt.expected) {
+				t.Fatalf("expected items (%v), got (%v)", tt.expected, nums)
+			}
+			for i := range nums {
+				if nums[i] != tt.expected[i] {
+					t.Fatalf("expected items (%v), got (%v)", tt.expected, nums)
+				}
+			}
+		})
+	}
+
+	var fetched []fakeINItem
+	if err := pgc.Select(&fetched, pgcq.IN("num", []int64{})); err == nil {
+		t.Errorf("expected error selecting by empty IN values")
+	}
+	if err := pgc.Select(&fetched, pgcq.Any("num", 1)); err == nil {
+		t.Errorf("expected error passing not a slice to ANY")
+	}
+}
+
 func TestSelectCustomData(t *testing.T) {
 	type rowData struct {
 		UserID       string
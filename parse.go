@@ -45,6 +45,11 @@ type model struct {
 	// PKPos is a position of a primary key.
 	PKPos int
 
+	// VersionName is a name of optimistic locking version column, marked with pgc:"version" tag.
+	VersionName string
+	// VersionPos is a position of a version field.
+	VersionPos int
+
 	// used if we don't want to fetch model's fields
 	NoFields bool
 
@@ -129,6 +134,24 @@ func (mod *model) getFields(columns []string) []*field {
 	return fields
 }
 
+// getFieldsStrict returns model fields exactly matching given columns, in the same order.
+// Unlike getFields primary key isn't added implicitly, and an error returned for unknown columns.
+func (mod *model) getFieldsStrict(columns []string) ([]*field, error) {
+	fields := make([]*field, 0, len(columns))
+ColumnsLoop:
+	for _, col := range columns {
+		for _, f := range mod.Fields {
+			if f.PGName == col {
+				fields = append(fields, f)
+				continue ColumnsLoop
+			}
+		}
+		return nil, fmt.Errorf("unrecognized column (%s) for table (%s)", col, mod.TableName)
+	}
+
+	return fields, nil
+}
+
 // Get a slice of the vals for interfacing with pgx
 func (pm *model) getVals(rowModel reflect.Value, fields []*field) []interface{} {
 	vals := make([]interface{}, 0, len(fields))
@@ -272,6 +295,31 @@ func (mod *model) getPK(rowModel reflect.Value) string {
 	return reflect.Indirect(rowModel).Field(mod.PKPos).String()
 }
 
+// getVersionField returns optimistic locking version field, or nil if model doesn't have one.
+func (mod *model) getVersionField() *field {
+	if mod.VersionName == "" {
+		return nil
+	}
+	for _, f := range mod.Fields {
+		if f.PGName == mod.VersionName {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// incrementVersion increments version field of a struct, the same way as db does during update.
+func (mod *model) incrementVersion(rowModel reflect.Value) {
+	versionVal := reflect.Indirect(rowModel).Field(mod.VersionPos)
+	switch versionVal.Kind() {
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		versionVal.SetUint(versionVal.Uint() + 1)
+	default:
+		versionVal.SetInt(versionVal.Int() + 1)
+	}
+}
+
 func parseModel(mm interface{}, requirePK bool) *model {
 	modType := reflect.TypeOf(mm)
 	typeName := modType.String()
@@ -354,6 +402,13 @@ func parseModel(mm interface{}, requirePK bool) *model {
 		if newField.PGName == mod.PKName {
 			mod.PKPos = i
 		}
+		if tagValue == "version" {
+			if mod.VersionName != "" {
+				panic(fmt.Sprintf("Multiple version fields for table (%s)", mod.TableName))
+			}
+			mod.VersionName = newField.PGName
+			mod.VersionPos = i
+		}
 
 		mod.Fields = append(mod.Fields, newField)
 	}
@@ -374,6 +429,14 @@ func (fi *field) setPGType(mod *model, tagVal string) {
 		fi.PGType = pgt_pk_string
 		mod.PKName = fi.PGName
 		return
+	case "version": // Optimistic locking version, incremented on each update
+		switch fi.ReflectKind {
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			fi.PGType = getPGBaseType(fi.ReflectKind)
+		default:
+			panic(fmt.Sprintf("Version field (%s) must be an integer", fi.GoName))
+		}
+		return
 	case "dt": // Custom time.Time. Use the dt struct tag for custom
 		// times since the below time.Time type assertion will fail
 		fi.PGType = pgt_date_time
@@ -88,6 +88,23 @@ func TestParseModelPanicPtrField(t *testing.T) {
 	assertPanicParseModel(t, &ptrAddress{})
 }
 
+func TestParseModelVersion(t *testing.T) {
+	type versioned struct {
+		ID  string
+		Rev int `pgc:"version"`
+	}
+	mod := parseModel(&versioned{}, true)
+	if mod.VersionName != "rev" || mod.VersionPos != 1 {
+		t.Errorf("Expected version column rev at position 1, got %s at %d", mod.VersionName, mod.VersionPos)
+	}
+
+	type badVersion struct {
+		ID  string
+		Rev string `pgc:"version"`
+	}
+	assertPanicParseModel(t, &badVersion{})
+}
+
 // This test should panic on the current not supported struct pointer field
 func TestParseModelPanicNonStruct(t *testing.T) {
 	type ptrAddress struct {
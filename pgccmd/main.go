@@ -2,14 +2,41 @@ package main
 
 import (
 	"os"
+	"strings"
 
 	"github.com/cliqueinc/pgc"
 )
 
 func main() {
-	pgc.InitFromEnv()
+	args, dsn := parseDSNFlag(os.Args)
+	if dsn != "" {
+		if err := pgc.InitFromDSN(dsn); err != nil {
+			panic(err)
+		}
+	} else {
+		pgc.InitFromEnv()
+	}
 	if err := pgc.RegisterMigrationPath(os.Getenv(pgc.GetConfig().EnvVarNameMigPath)); err != nil {
 		panic(err)
 	}
-	pgc.RunCmd(os.Args...)
+	pgc.RunCmd(args...)
+}
+
+// parseDSNFlag extracts connection string passed as `--dsn value` or `--dsn=value`, returning the rest of args.
+func parseDSNFlag(args []string) ([]string, string) {
+	var dsn string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dsn" && i+1 < len(args):
+			dsn = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--dsn="):
+			dsn = strings.TrimPrefix(args[i], "--dsn=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	return rest, dsn
 }
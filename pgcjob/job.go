@@ -0,0 +1,249 @@
+// Package pgcjob implements durable job queue on top of pgc, stored in postgres table.
+// Workers take jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any number of workers may process
+// the same queue concurrently without taking the same job twice.
+package pgcjob
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cliqueinc/pgc"
+	"github.com/cliqueinc/pgc/pgcq"
+	"github.com/cliqueinc/pgc/util"
+)
+
+// Job statuses.
+const (
+	// StatusPending means job waits to be run at RunAt.
+	StatusPending = "pending"
+	// StatusRunning means job is taken by worker, and may be taken again once LockedUntil passes.
+	StatusRunning = "running"
+	// StatusDead means job failed MaxAttempts times and won't be run anymore (dead letter), unless retried.
+	StatusDead = "dead"
+)
+
+// TableName is a name of jobs table.
+const TableName = "pgc_jobs"
+
+// NotifyChannel is a channel of notifications about enqueued jobs, payload is a queue name.
+const NotifyChannel = "pgc_jobs"
+
+// Queue defaults.
+const (
+	DefaultPollInterval      = 5 * time.Second
+	DefaultVisibilityTimeout = 5 * time.Minute
+	DefaultMaxAttempts       = 10
+	DefaultConcurrency       = 1
+)
+
+// Job is a queued job. Table is created with pgc.CreateTable(&pgcjob.Job{}), or see Schema for migration.
+type Job struct {
+	ID       string
+	Queue    string
+	Status   string
+	Priority int
+	// Payload is a job data, see Decode.
+	Payload     map[string]interface{}
+	Attempts    int
+	MaxAttempts int
+	// RunAt is a time job is scheduled to run at.
+	RunAt time.Time
+	// LockedUntil is a visibility timeout of running job: in case the job isn't finished till that time
+	// (e.g. worker crashed), it's taken by another worker.
+	LockedUntil time.Time
+	LastError   string
+	Created     time.Time
+	Updated     time.Time
+	Version     int64 `pgc:"version"`
+}
+
+// TableName returns jobs table name.
+func (j *Job) TableName() string {
+	return TableName
+}
+
+// Decode decodes job payload into v.
+func (j *Job) Decode(v interface{}) error {
+	data, err := json.Marshal(j.Payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Schema returns sql, creating jobs table and its index, to be placed in schema migration.
+func Schema() string {
+	return pgc.GenerateSchema(&Job{}) + fmt.Sprintf(
+		"\nCREATE INDEX %s_fetch_idx ON \"%s\" (queue, status, priority DESC, run_at);\n",
+		TableName,
+		TableName,
+	)
+}
+
+// Config describes queue settings.
+type Config struct {
+	// Concurrency is a number of jobs processed by Work concurrently, DefaultConcurrency used if 0.
+	Concurrency int
+	// PollInterval is an interval of checking for new jobs in case queue is empty, DefaultPollInterval used if 0.
+	PollInterval time.Duration
+	// VisibilityTimeout is a max time of job processing, after which the job is taken by another worker.
+	// Handler context is cancelled once timeout is exceeded. DefaultVisibilityTimeout used if 0.
+	VisibilityTimeout time.Duration
+	// MaxAttempts is a default max number of job attempts, DefaultMaxAttempts used if 0.
+	MaxAttempts int
+	// Backoff returns delay before retry of failed job, attempt starts from 1. Exponential backoff used if nil.
+	Backoff func(attempt int) time.Duration
+
+	// Notify enables LISTEN/NOTIFY wakeups, so workers take enqueued jobs immediately, instead of waiting for the next poll.
+	Notify bool
+
+	// ErrorHandler is called on errors of workers, which aren't returned to the caller, e.g. failure to take a job.
+	ErrorHandler func(err error)
+}
+
+// defaultBackoff doubles retry delay starting from 1 second, up to 1 hour.
+func defaultBackoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return time.Hour
+	}
+
+	return time.Duration(1<<uint(attempt-1)) * time.Second
+}
+
+// Queue is a named job queue.
+type Queue struct {
+	name    string
+	adapter *pgc.Adapter
+	config  Config
+}
+
+// New creates queue with name, performing operations with adapter. If config is nil, default settings used.
+func New(a *pgc.Adapter, name string, config *Config) *Queue {
+	q := &Queue{name: name, adapter: a}
+	if config != nil {
+		q.config = *config
+	}
+	if q.config.Concurrency == 0 {
+		q.config.Concurrency = DefaultConcurrency
+	}
+	if q.config.PollInterval == 0 {
+		q.config.PollInterval = DefaultPollInterval
+	}
+	if q.config.VisibilityTimeout == 0 {
+		q.config.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if q.config.MaxAttempts == 0 {
+		q.config.MaxAttempts = DefaultMaxAttempts
+	}
+	if q.config.Backoff == nil {
+		q.config.Backoff = defaultBackoff
+	}
+
+	return q
+}
+
+// Name returns queue name.
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// EnqueueOptions describes settings of enqueued job.
+type EnqueueOptions struct {
+	// Priority of the job, jobs with higher priority are taken first.
+	Priority int
+	// RunAt schedules the job to run not earlier than given time, the job is run asap if empty.
+	RunAt time.Time
+	// MaxAttempts overrides queue max attempts.
+	MaxAttempts int
+}
+
+// Enqueue adds job with payload to queue. Payload needs to be encoded to json object (e.g. struct or map).
+// Job is enqueued within transaction bound to ctx (see pgc.ContextWithTx) if any,
+// so the job is taken by workers only once the transaction is committed.
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}, opts *EnqueueOptions) (*Job, error) {
+	if opts == nil {
+		opts = &EnqueueOptions{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode job payload: %v", err)
+	}
+	var payloadMap map[string]interface{}
+	if err := json.Unmarshal(data, &payloadMap); err != nil {
+		return nil, errors.New("job payload must be encoded to json object")
+	}
+	if payloadMap == nil {
+		payloadMap = map[string]interface{}{}
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:          util.NewGuid(),
+		Queue:       q.name,
+		Status:      StatusPending,
+		Priority:    opts.Priority,
+		Payload:     payloadMap,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt.UTC(),
+		Created:     now,
+		Updated:     now,
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.config.MaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	a := q.adapter.WithContext(ctx)
+	if err := a.Insert(job); err != nil {
+		return nil, err
+	}
+	if q.config.Notify {
+		if err := a.Notify(NotifyChannel, q.name); err != nil {
+			return nil, err
+		}
+	}
+
+	return job, nil
+}
+
+// Dead returns up to limit jobs, which failed all attempts (dead letter), the latest failed first.
+func (q *Queue) Dead(ctx context.Context, limit int) ([]Job, error) {
+	var jobs []Job
+	err := q.adapter.WithContext(ctx).Select(
+		&jobs,
+		pgcq.Equal("queue", q.name),
+		pgcq.Equal("status", StatusDead),
+		pgcq.Order("updated", pgcq.DESC),
+		pgcq.Limit(limit),
+	)
+
+	return jobs, err
+}
+
+// Retry schedules dead job to run again asap, resetting its attempts.
+func (q *Queue) Retry(ctx context.Context, job *Job) error {
+	if job.Status != StatusDead {
+		return fmt.Errorf("cannot retry job in (%s) status", job.Status)
+	}
+	now := time.Now().UTC()
+	job.Status = StatusPending
+	job.Attempts = 0
+	job.RunAt = now
+	job.Updated = now
+
+	a := q.adapter.WithContext(ctx)
+	if err := a.UpdateColumns(job, "status", "attempts", "run_at", "updated"); err != nil {
+		return err
+	}
+	if q.config.Notify {
+		return a.Notify(NotifyChannel, q.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package pgcjob_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cliqueinc/pgc"
+	"github.com/cliqueinc/pgc/pgcjob"
+	"github.com/cliqueinc/pgc/util"
+)
+
+func TestMain(m *testing.M) {
+	envDBName := os.Getenv("POSTGRES_DB")
+
+	var shouldDropDB bool
+	if !strings.HasPrefix(envDBName, "pgc_tmp") {
+		envDBName = pgc.CreateDB("pgc_tmp")
+		shouldDropDB = true
+	}
+	pgc.MustInit(envDBName, "localhost", "", "", false, 0)
+	pgc.MustCreateTable(&pgcjob.Job{})
+
+	exitVal := m.Run()
+	pgc.ClosePool()
+	if shouldDropDB {
+		pgc.DropDB(envDBName)
+	}
+	os.Exit(exitVal)
+}
+
+type emailPayload struct {
+	To string `json:"to"`
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+	})
+
+	if _, err := q.Enqueue(ctx, emailPayload{To: "low@example.com"}, nil); err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, emailPayload{To: "high@example.com"}, &pgcjob.EnqueueOptions{Priority: 10}); err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, emailPayload{To: "later@example.com"}, &pgcjob.EnqueueOptions{RunAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+
+	var processed []string
+	handler := func(ctx context.Context, job *pgcjob.Job) error {
+		var p emailPayload
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		processed = append(processed, p.To)
+		return nil
+	}
+	for {
+		ok, err := q.RunOne(ctx, handler)
+		if err != nil {
+			t.Fatalf("failed to run job: %v", err)
+		}
+		if !ok {
+			break
+		}
+	}
+	if len(processed) != 2 || processed[0] != "high@example.com" || processed[1] != "low@example.com" {
+		t.Errorf("expected jobs to be processed by priority excluding scheduled one, got: %v", processed)
+	}
+
+	t.Run("dead letter", func(t *testing.T) {
+		q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{
+			MaxAttempts: 2,
+			Backoff:     func(int) time.Duration { return 0 },
+		})
+		if _, err := q.Enqueue(ctx, emailPayload{To: "fail@example.com"}, nil); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		var attempts int
+		failing := func(ctx context.Context, job *pgcjob.Job) error {
+			attempts++
+			if attempts == 2 {
+				panic("failed")
+			}
+			return errors.New("failed")
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := q.RunOne(ctx, failing); err != nil {
+				t.Fatalf("failed to run job: %v", err)
+			}
+		}
+		if attempts != 2 {
+			t.Errorf("expected job to be run 2 times, was run %d times", attempts)
+		}
+
+		dead, err := q.Dead(ctx, 10)
+		if err != nil {
+			t.Fatalf("failed to get dead jobs: %v", err)
+		}
+		if len(dead) != 1 || dead[0].Attempts != 2 || !strings.Contains(dead[0].LastError, "failed") {
+			t.Fatalf("expected a single dead job with 2 attempts, got: %+v", dead)
+		}
+
+		if err := q.Retry(ctx, &dead[0]); err != nil {
+			t.Fatalf("failed to retry dead job: %v", err)
+		}
+		if ok, err := q.RunOne(ctx, handler); err != nil || !ok {
+			t.Errorf("expected retried job to be run, ok: %v, err: %v", ok, err)
+		}
+	})
+
+	t.Run("visibility timeout", func(t *testing.T) {
+		q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{VisibilityTimeout: 100 * time.Millisecond})
+		if _, err := q.Enqueue(ctx, emailPayload{To: "slow@example.com"}, nil); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		_, err := q.RunOne(ctx, func(ctx context.Context, job *pgcjob.Job) error {
+			// the job is taken by another worker once visibility timeout passes
+			<-ctx.Done()
+			if ok, err := q.RunOne(context.Background(), handler); err != nil || !ok {
+				t.Errorf("expected job to be taken again, ok: %v, err: %v", ok, err)
+			}
+			return nil
+		})
+		if err != pgc.ErrStaleObject {
+			t.Errorf("expected (%v) error finishing job taken by another worker, got (%v)", pgc.ErrStaleObject, err)
+		}
+	})
+
+	t.Run("visibility timeout of the last attempt", func(t *testing.T) {
+		q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{
+			MaxAttempts:       1,
+			VisibilityTimeout: 100 * time.Millisecond,
+		})
+		if _, err := q.Enqueue(ctx, emailPayload{To: "crash@example.com"}, nil); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		q.RunOne(ctx, func(ctx context.Context, job *pgcjob.Job) error {
+			<-ctx.Done()
+			if ok, err := q.RunOne(context.Background(), handler); err != nil || ok {
+				t.Errorf("expected job without attempts left not to be run again, ok: %v, err: %v", ok, err)
+			}
+			return nil
+		})
+		dead, err := q.Dead(ctx, 10)
+		if err != nil {
+			t.Fatalf("failed to get dead jobs: %v", err)
+		}
+		if len(dead) != 1 || dead[0].Attempts != 1 {
+			t.Errorf("expected a single dead job with 1 attempt, got: %+v", dead)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{MaxAttempts: 1})
+		if _, err := q.Enqueue(ctx, emailPayload{To: "shutdown@example.com"}, nil); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		workerCtx, cancel := context.WithCancel(ctx)
+		_, err := q.RunOne(workerCtx, func(ctx context.Context, job *pgcjob.Job) error {
+			cancel()
+			return ctx.Err()
+		})
+		if err != nil {
+			t.Fatalf("failed to release interrupted job: %v", err)
+		}
+
+		var attempts int
+		ok, err := q.RunOne(ctx, func(ctx context.Context, job *pgcjob.Job) error {
+			attempts = job.Attempts
+			return nil
+		})
+		if err != nil || !ok || attempts != 1 {
+			t.Errorf("expected interrupted job to be run again as the first attempt, ok: %v, attempts: %d, err: %v", ok, attempts, err)
+		}
+	})
+
+	t.Run("work", func(t *testing.T) {
+		q := pgcjob.New(pgc.NewAdapter(), util.RandomString(10), &pgcjob.Config{
+			Concurrency:  2,
+			PollInterval: time.Minute,
+			Notify:       true,
+		})
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		done := make(chan string)
+		go q.Work(ctx, func(ctx context.Context, job *pgcjob.Job) error {
+			var p emailPayload
+			if err := job.Decode(&p); err != nil {
+				return err
+			}
+			done <- p.To
+			return nil
+		})
+
+		// give workers time to start listening, so the job is taken on notification instead of poll
+		time.Sleep(500 * time.Millisecond)
+		if _, err := q.Enqueue(ctx, emailPayload{To: "notify@example.com"}, nil); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+		select {
+		case to := <-done:
+			if to != "notify@example.com" {
+				t.Errorf("unexpected job processed: %s", to)
+			}
+		case <-ctx.Done():
+			t.Errorf("job wasn't processed")
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package pgcjob
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cliqueinc/pgc"
+	"github.com/cliqueinc/pgc/pgcq"
+)
+
+// Handler processes job. In case handler returns an error or panics, the job is retried with backoff,
+// or moved to dead letter once max attempts are exhausted.
+type Handler func(ctx context.Context, job *Job) error
+
+// Work processes queue jobs with handler until ctx is done. Jobs are processed concurrently
+// according to queue concurrency.
+func (q *Queue) Work(ctx context.Context, handler Handler) error {
+	wake := make(chan struct{}, q.config.Concurrency)
+	if q.config.Notify {
+		go q.listen(ctx, wake)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, handler, wake)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// work takes and processes jobs one by one until ctx is done. In case there are no jobs,
+// waits for poll interval or wakeup.
+func (q *Queue) work(ctx context.Context, handler Handler, wake <-chan struct{}) {
+	for ctx.Err() == nil {
+		job, err := q.take(ctx)
+		if err != nil && ctx.Err() == nil {
+			q.reportError(fmt.Errorf("cannot take job from queue (%s): %v", q.name, err))
+		}
+		if job != nil {
+			q.process(ctx, job, handler)
+			continue
+		}
+
+		timer := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// RunOne takes a single job and processes it with handler, reports whether there was a job to process.
+func (q *Queue) RunOne(ctx context.Context, handler Handler) (bool, error) {
+	job, err := q.take(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	return true, q.complete(ctx, job, q.run(ctx, job, handler))
+}
+
+// take takes the next job ready to run: pending one, or running one with expired visibility timeout.
+// Running job with expired visibility timeout, which has no attempts left (e.g. worker crashed on the last attempt),
+// is moved to dead letter instead.
+func (q *Queue) take(ctx context.Context) (*Job, error) {
+	var job *Job
+	err := q.adapter.RunInTx(ctx, nil, func(tx *pgc.TxAdapter) error {
+		job = nil
+		now := time.Now().UTC()
+		for {
+			var jobs []Job
+			err := tx.Select(
+				&jobs,
+				pgcq.Equal("queue", q.name),
+				pgcq.OR(
+					pgcq.AND(pgcq.Equal("status", StatusPending), pgcq.LessOrEqual("run_at", now)),
+					pgcq.AND(pgcq.Equal("status", StatusRunning), pgcq.LessOrEqual("locked_until", now)),
+				),
+				pgcq.Order("priority", pgcq.DESC),
+				pgcq.Order("run_at", pgcq.ASC),
+				pgcq.Limit(1),
+				pgcq.ForUpdate(pgcq.SkipLocked()),
+			)
+			if err != nil || len(jobs) == 0 {
+				return err
+			}
+
+			j := &jobs[0]
+			j.Updated = now
+			if j.Status == StatusRunning && j.Attempts >= j.MaxAttempts {
+				j.Status = StatusDead
+				j.LastError = "visibility timeout exceeded"
+				if err := tx.UpdateColumns(j, "status", "last_error", "updated"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			j.Status = StatusRunning
+			j.Attempts++
+			j.LockedUntil = now.Add(q.config.VisibilityTimeout)
+			if err := tx.UpdateColumns(j, "status", "attempts", "locked_until", "updated"); err != nil {
+				return err
+			}
+			job = j
+
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// process runs job handler and records the result.
+func (q *Queue) process(ctx context.Context, job *Job, handler Handler) {
+	if err := q.complete(ctx, job, q.run(ctx, job, handler)); err != nil {
+		q.reportError(fmt.Errorf("cannot finish job (%s): %v", job.ID, err))
+	}
+}
+
+// complete records the result of job run. Job failed once worker ctx is done (e.g. on shutdown)
+// is released back to queue without counting the attempt.
+func (q *Queue) complete(ctx context.Context, job *Job, jobErr error) error {
+	if jobErr != nil && ctx.Err() != nil {
+		return q.release(job)
+	}
+
+	return q.finish(job, jobErr)
+}
+
+// run calls handler within job visibility timeout, converting panic into an error.
+func (q *Queue) run(ctx context.Context, job *Job, handler Handler) (err error) {
+	ctx, cancel := context.WithDeadline(ctx, job.LockedUntil)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panic: %v", p)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// finish deletes successfully processed job, or schedules failed job for retry, or moves it to dead letter.
+// Job finish ignores worker context, so the result isn't lost on shutdown. In case the job was taken
+// by another worker due to visibility timeout, pgc.ErrStaleObject is returned.
+func (q *Queue) finish(job *Job, jobErr error) error {
+	a := q.adapter.WithContext(context.Background())
+	if jobErr == nil {
+		deleted, err := a.DeleteRows(&Job{}, pgcq.Equal("id", job.ID), pgcq.Equal("version", job.Version))
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return pgc.ErrStaleObject
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	job.LastError = jobErr.Error()
+	job.Updated = now
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = StatusDead
+	} else {
+		job.Status = StatusPending
+		job.RunAt = now.Add(q.config.Backoff(job.Attempts))
+	}
+
+	return a.UpdateColumns(job, "status", "run_at", "last_error", "updated")
+}
+
+// release returns interrupted job back to queue to be run asap, reverting its attempt.
+func (q *Queue) release(job *Job) error {
+	now := time.Now().UTC()
+	job.Status = StatusPending
+	job.Attempts--
+	job.RunAt = now
+	job.Updated = now
+
+	return q.adapter.WithContext(context.Background()).UpdateColumns(job, "status", "attempts", "run_at", "updated")
+}
+
+// listen wakes up workers once job is enqueued, until ctx is done.
+func (q *Queue) listen(ctx context.Context, wake chan<- struct{}) {
+	var (
+		sub *pgc.Subscription
+		err error
+	)
+	for {
+		if sub, err = q.adapter.Subscribe(ctx, NotifyChannel); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		q.reportError(fmt.Errorf("cannot listen for jobs of queue (%s): %v", q.name, err))
+
+		timer := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	for n := range sub.Notifications() {
+		if n.Payload != q.name {
+			continue
+		}
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reportError passes worker error to error handler, if any.
+func (q *Queue) reportError(err error) {
+	if q.config.ErrorHandler != nil {
+		q.config.ErrorHandler(err)
+	}
+}
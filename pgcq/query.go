@@ -3,10 +3,14 @@ package pgcq
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // comparison operators
@@ -26,6 +30,14 @@ const (
 	DESC = "DESC"
 )
 
+// row lock strengths, see https://www.postgresql.org/docs/current/sql-select.html#SQL-FOR-UPDATE-SHARE
+const (
+	lockUpdate      = "UPDATE"
+	lockNoKeyUpdate = "NO KEY UPDATE"
+	lockShare       = "SHARE"
+	lockKeyShare    = "KEY SHARE"
+)
+
 // these flags describe whether query option is allowed for a specific db operation.
 const (
 	OpSelect = "select"
@@ -42,6 +54,8 @@ const (
 	typeHaving
 	typeColumns
 	typeJoin
+	typeKeyset
+	typeLock
 	// typeQueryAll enforses quering all data (like where 1=1),
 	// used to prevent unintentional update or delete of all rows in table
 	typeQueryAll
@@ -55,6 +69,10 @@ type Query struct {
 	limit, offset int
 	order         []string
 	group         []string
+	locks         []string
+	hasWhere      bool
+	affectsRows   bool
+	tieBreaker    string
 	queryType     string
 
 	Args       []interface{}
@@ -63,6 +81,89 @@ type Query struct {
 	Having     string
 	IsQueryAll bool
 	Joins      []JoinConfig
+	Keyset     *Keyset
+}
+
+// Limit returns query limit, 0 means no limit.
+func (q *Query) Limit() int {
+	return q.limit
+}
+
+// HasWhere reports whether query has any where conditions.
+func (q *Query) HasWhere() bool {
+	return q.hasWhere
+}
+
+// AffectsRows reports whether query has options affecting which rows are selected (where conditions,
+// order, pagination, joins etc.), unlike columns or row lock options.
+func (q *Query) AffectsRows() bool {
+	return q.affectsRows
+}
+
+// Locking reports whether query locks selected rows.
+func (q *Query) Locking() bool {
+	return len(q.locks) != 0
+}
+
+// Keyset describes keyset (seek) pagination.
+type Keyset struct {
+	Cursor    Cursor
+	Direction string
+	Columns   []string
+}
+
+// Cursor is an opaque position of keyset pagination, which is safe to be passed to api clients.
+// Empty cursor points to the first page.
+type Cursor string
+
+// EncodeCursor encodes values of keyset columns of the last fetched row into cursor.
+// Pointer values are dereferenced, nil values aren't allowed.
+func EncodeCursor(values ...interface{}) (Cursor, error) {
+	textValues := make([]string, 0, len(values))
+	for _, val := range values {
+		rv := reflect.ValueOf(val)
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return "", errors.New("cursor values cannot be nil")
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() {
+			return "", errors.New("cursor values cannot be nil")
+		}
+		val = rv.Interface()
+
+		switch v := val.(type) {
+		case time.Time:
+			textValues = append(textValues, v.Format(time.RFC3339Nano))
+		case string:
+			textValues = append(textValues, v)
+		case fmt.Stringer:
+			textValues = append(textValues, v.String())
+		default:
+			textValues = append(textValues, fmt.Sprint(v))
+		}
+	}
+	data, err := json.Marshal(textValues)
+	if err != nil {
+		return "", err
+	}
+
+	return Cursor(base64.RawURLEncoding.EncodeToString(data)), nil
+}
+
+// values decodes cursor into text representation of keyset column values.
+func (c Cursor) values() ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return values, nil
 }
 
 // JoinConfig describes join config.
@@ -121,12 +222,18 @@ func where(field string, cmp string, value interface{}) Option {
 
 		argNum := len(q.Args) + 1
 		q.Args = append(q.Args, value)
-		if !strings.Contains(field, "(") && !(strings.HasPrefix(field, "\"") && strings.HasSuffix(field, "\"")) {
-			field = "\"" + field + "\""
-		}
 
-		return fmt.Sprintf("%s %s $%d", field, string(cmp), argNum), typeQuery, nil
+		return fmt.Sprintf("%s %s $%d", quoteField(field), string(cmp), argNum), typeQuery, nil
+	}
+}
+
+// quoteField quotes field name, unless it's an expression or already quoted.
+func quoteField(field string) string {
+	if !strings.Contains(field, "(") && !(strings.HasPrefix(field, "\"") && strings.HasSuffix(field, "\"")) {
+		return "\"" + field + "\""
 	}
+
+	return field
 }
 
 // Raw adds raw where query. Arguments in query expected to be marked as '?'.
@@ -170,24 +277,74 @@ func All() Option {
 	}
 }
 
-// IN adds IN construction to query.
-func IN(field string, values ...string) Option {
+// IN adds IN construction to query. Values may be of any type, a single slice (e.g. []string or []int64)
+// is expanded into values, e.g. pgcq.IN("id", 1, 2, 3) or pgcq.IN("id", ids).
+// String slice is passed as is, not spread: pgcq.IN("id", ids...) doesn't compile for ids []string.
+func IN(field string, values ...interface{}) Option {
+	return in(field, "IN", values)
+}
+
+// NotIN adds NOT IN construction to query, values are passed the same way as to IN.
+func NotIN(field string, values ...interface{}) Option {
+	return in(field, "NOT IN", values)
+}
+
+// in adds IN or NOT IN construction to query, every value is bound as a separate argument.
+func in(field string, op string, values []interface{}) Option {
 	return func(q *Query) (string, int, error) {
 		if field == "" {
 			return "", 0, errors.New("field cannot be empty")
 		}
-		if len(values) == 0 {
-			return "", 0, errors.New("IN values cannot be empty")
+		vals := expandSlice(values)
+		if len(vals) == 0 {
+			return "", 0, fmt.Errorf("%s values cannot be empty", op)
 		}
 
-		queryArgs := make([]string, 0, len(values))
+		queryArgs := make([]string, 0, len(vals))
 		start := len(q.Args)
-		for i, val := range values {
+		for i, val := range vals {
 			queryArgs = append(queryArgs, "$"+strconv.Itoa(start+i+1))
 			q.Args = append(q.Args, val)
 		}
 
-		return fmt.Sprintf("\"%s\" IN ("+strings.Join(queryArgs, ",")+")", field), typeQuery, nil
+		return fmt.Sprintf("%s %s ("+strings.Join(queryArgs, ",")+")", quoteField(field), op), typeQuery, nil
+	}
+}
+
+// expandSlice expands a single slice (except []byte) into values.
+func expandSlice(values []interface{}) []interface{} {
+	if len(values) != 1 {
+		return values
+	}
+	rv := reflect.ValueOf(values[0])
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+
+	expanded := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		expanded = append(expanded, rv.Index(i).Interface())
+	}
+
+	return expanded
+}
+
+// Any adds field = ANY($1) construction to query, binding values slice (e.g. []int64 or []string) as a single
+// array argument. Unlike IN, statement text doesn't depend on a number of values, so the statement plan may be cached.
+// Empty slice matches no rows.
+func Any(field string, values interface{}) Option {
+	return func(q *Query) (string, int, error) {
+		if field == "" {
+			return "", 0, errors.New("field cannot be empty")
+		}
+		if reflect.ValueOf(values).Kind() != reflect.Slice {
+			return "", 0, fmt.Errorf("ANY values must be a slice, (%T) given", values)
+		}
+
+		argNum := len(q.Args) + 1
+		q.Args = append(q.Args, values)
+
+		return fmt.Sprintf("%s = ANY($%d)", quoteField(field), argNum), typeQuery, nil
 	}
 }
 
@@ -314,6 +471,44 @@ func Order(field string, orderBy string) Option {
 	}
 }
 
+// Seek adds keyset (seek) pagination to select: rows are ordered by given columns in the given direction,
+// and only rows positioned after the cursor are fetched. The last column needs to be unique (like primary key)
+// to break ties, see SeekTieBreaker. Cannot be combined with Order.
+// If multiple seeks specified, the last one will be set.
+func Seek(cursor Cursor, direction string, columns ...string) Option {
+	return func(q *Query) (string, int, error) {
+		if q.queryType != OpSelect {
+			return "", 0, fmt.Errorf("cannot use seek in (%s)", q.queryType)
+		}
+		if strings.ToLower(direction) != "asc" && strings.ToLower(direction) != "desc" {
+			return "", 0, fmt.Errorf("unknown order %s", direction)
+		}
+		if len(columns) == 0 {
+			return "", 0, errors.New("no columns specified for seek")
+		}
+
+		q.Keyset = &Keyset{Cursor: cursor, Direction: strings.ToUpper(direction), Columns: columns}
+		return "", typeKeyset, nil
+	}
+}
+
+// SeekTieBreaker appends unique column (like primary key) to seek columns, unless it's the last seek column already,
+// so rows with the same values of seek columns are ordered stably. The cursor is expected to include the column value.
+// pgc.SelectPage adds primary key tie-breaker automatically.
+func SeekTieBreaker(column string) Option {
+	return func(q *Query) (string, int, error) {
+		if q.queryType != OpSelect {
+			return "", 0, fmt.Errorf("cannot use seek in (%s)", q.queryType)
+		}
+		if column == "" {
+			return "", 0, errors.New("seek tie-breaker column cannot be empty")
+		}
+
+		q.tieBreaker = column
+		return "", typeKeyset, nil
+	}
+}
+
 // Columns specifies columns that needs to be fetched. By default all columns are fetched.
 func Columns(columns ...string) Option {
 	return func(q *Query) (string, int, error) {
@@ -356,6 +551,95 @@ func Join(structPtr interface{}, condition string, columns ...string) Option {
 	}
 }
 
+// LockOption modifies row lock clause.
+type LockOption func(l *lock)
+
+// lock describes row lock clause.
+type lock struct {
+	strength   string
+	tables     []string
+	noWait     bool
+	skipLocked bool
+}
+
+// NoWait makes select fail immediately, instead of waiting, in case any selected row is already locked.
+func NoWait() LockOption {
+	return func(l *lock) {
+		l.noWait = true
+	}
+}
+
+// SkipLocked makes select skip rows, which are already locked, e.g. in order to implement a queue.
+func SkipLocked() LockOption {
+	return func(l *lock) {
+		l.skipLocked = true
+	}
+}
+
+// Of restricts lock to rows of the given tables, in case other tables are joined.
+func Of(tables ...string) LockOption {
+	return func(l *lock) {
+		l.tables = append(l.tables, tables...)
+	}
+}
+
+// ForUpdate locks selected rows against concurrent update, delete or lock, until the end of transaction.
+func ForUpdate(opts ...LockOption) Option {
+	return lockRows(lockUpdate, opts)
+}
+
+// ForNoKeyUpdate is a weaker version of ForUpdate, which doesn't block ForKeyShare locks,
+// e.g. inserts of rows referencing locked ones.
+func ForNoKeyUpdate(opts ...LockOption) Option {
+	return lockRows(lockNoKeyUpdate, opts)
+}
+
+// ForShare locks selected rows against concurrent update or delete, but allows other shared locks.
+func ForShare(opts ...LockOption) Option {
+	return lockRows(lockShare, opts)
+}
+
+// ForKeyShare is a weaker version of ForShare, which blocks only delete and update of key columns.
+func ForKeyShare(opts ...LockOption) Option {
+	return lockRows(lockKeyShare, opts)
+}
+
+// lockRows adds row lock clause of given strength to select. Lock makes sense only within transaction,
+// since locks are released at the end of transaction. Multiple locks (e.g. of different tables) may be specified.
+func lockRows(strength string, opts []LockOption) Option {
+	return func(q *Query) (string, int, error) {
+		if q.queryType != OpSelect {
+			return "", 0, fmt.Errorf("cannot use row lock in (%s)", q.queryType)
+		}
+
+		l := &lock{strength: strength}
+		for _, opt := range opts {
+			opt(l)
+		}
+		if l.noWait && l.skipLocked {
+			return "", 0, errors.New("cannot combine nowait with skip locked")
+		}
+
+		clause := "FOR " + l.strength
+		if len(l.tables) != 0 {
+			tables := make([]string, 0, len(l.tables))
+			for _, table := range l.tables {
+				tables = append(tables, "\""+table+"\"")
+			}
+			clause += " OF " + strings.Join(tables, ", ")
+		}
+		if l.noWait {
+			clause += " NOWAIT"
+		}
+		if l.skipLocked {
+			clause += " SKIP LOCKED"
+		}
+
+		q.locks = append(q.locks, clause)
+		return "", typeLock, nil
+	}
+}
+
 // Build builds sql query from given query option
 func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query, error) {
 	var (
@@ -371,6 +655,9 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 		if err != nil {
 			return nil, err
 		}
+		if optType != typeColumns && optType != typeLock {
+			stmt.affectsRows = true
+		}
 		if optType == typeQueryAll {
 			isQueryAll = true
 			continue
@@ -385,9 +672,54 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 		whereOpts = append(whereOpts, optQuery)
 	}
 
+	if stmt.Keyset != nil {
+		if len(stmt.order) != 0 {
+			return nil, errors.New("cannot combine order with seek")
+		}
+		if columns := stmt.Keyset.Columns; stmt.tieBreaker != "" && columns[len(columns)-1] != stmt.tieBreaker {
+			keyset := *stmt.Keyset
+			keyset.Columns = append(append(make([]string, 0, len(columns)+1), columns...), stmt.tieBreaker)
+			stmt.Keyset = &keyset
+		}
+		keysetColumns := make([]string, 0, len(stmt.Keyset.Columns))
+		for _, col := range stmt.Keyset.Columns {
+			keysetColumns = append(keysetColumns, "\""+col+"\"")
+			stmt.order = append(stmt.order, fmt.Sprintf("\"%s\" %s", col, stmt.Keyset.Direction))
+		}
+		if stmt.Keyset.Cursor != "" {
+			values, err := stmt.Keyset.Cursor.values()
+			if err != nil {
+				return nil, err
+			}
+			if len(values) != len(stmt.Keyset.Columns) {
+				return nil, errors.New("cursor doesn't match seek columns")
+			}
+			cmp := gt
+			if stmt.Keyset.Direction == DESC {
+				cmp = lt
+			}
+			argNums := make([]string, 0, len(values))
+			for _, val := range values {
+				stmt.Args = append(stmt.Args, val)
+				argNums = append(argNums, "$"+strconv.Itoa(len(stmt.Args)))
+			}
+			whereOpts = append(whereOpts, fmt.Sprintf(
+				"(%s) %s (%s)",
+				strings.Join(keysetColumns, ", "),
+				cmp,
+				strings.Join(argNums, ", "),
+			))
+		}
+	}
+
+	if len(stmt.locks) != 0 && (len(stmt.group) != 0 || stmt.Having != "") {
+		return nil, errors.New("cannot combine row lock with group by or having")
+	}
+
 	var query string
 	if len(whereOpts) != 0 {
 		query = "WHERE " + strings.Join(whereOpts, " AND ")
+		stmt.hasWhere = true
 	}
 	if len(stmt.group) != 0 {
 		query += " GROUP BY " + strings.Join(stmt.group, ",") + " "
@@ -407,6 +739,9 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 	if stmt.offset != 0 {
 		query += fmt.Sprintf(" OFFSET %d", stmt.offset)
 	}
+	if len(stmt.locks) != 0 {
+		query += " " + strings.Join(stmt.locks, " ")
+	}
 
 	stmt.Query = query
 	stmt.IsQueryAll = isQueryAll
@@ -0,0 +1,86 @@
+package pgcq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSeek(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cursor, err := EncodeCursor(&created, "id1")
+	if err != nil {
+		t.Fatalf("cannot encode cursor: %v", err)
+	}
+	values, err := cursor.values()
+	if err != nil {
+		t.Fatalf("cannot decode cursor: %v", err)
+	}
+	if values[0] != created.Format(time.RFC3339Nano) {
+		t.Errorf("expected pointer value to be dereferenced, got (%s)", values[0])
+	}
+	var nilTime *time.Time
+	if _, err := EncodeCursor(nilTime); err == nil {
+		t.Errorf("expected error encoding nil value")
+	}
+
+	q, err := Build([]Option{Seek(cursor, DESC, "created"), SeekTieBreaker("id"), Limit(10)}, OpSelect)
+	if err != nil {
+		t.Fatalf("cannot build query: %v", err)
+	}
+	expected := `WHERE ("created", "id") < ($1, $2) ORDER BY "created" DESC, "id" DESC LIMIT 10`
+	if q.Query != expected {
+		t.Errorf("expected query (%s), got (%s)", expected, q.Query)
+	}
+	if strings.Join(q.Keyset.Columns, ",") != "created,id" {
+		t.Errorf("expected tie-breaker appended to keyset columns, got %v", q.Keyset.Columns)
+	}
+
+	// tie-breaker isn't duplicated
+	q, err = Build([]Option{SeekTieBreaker("id"), Seek(cursor, DESC, "created", "id")}, OpSelect)
+	if err != nil {
+		t.Fatalf("cannot build query: %v", err)
+	}
+	if len(q.Keyset.Columns) != 2 {
+		t.Errorf("expected 2 keyset columns, got %v", q.Keyset.Columns)
+	}
+
+	if _, err := Build([]Option{Seek(cursor, DESC, "created")}, OpSelect); err == nil {
+		t.Errorf("expected error for cursor not matching seek columns")
+	}
+}
+
+func TestBuildIN(t *testing.T) {
+	cases := []struct {
+		name  string
+		opt   Option
+		query string
+		args  []interface{}
+	}{
+		{"int64 slice", IN("id", []int64{1, 2}), `WHERE "id" IN ($1,$2) LIMIT 1000`, []interface{}{int64(1), int64(2)}},
+		{"string slice", NotIN("id", []string{"a", "b"}), `WHERE "id" NOT IN ($1,$2) LIMIT 1000`, []interface{}{"a", "b"}},
+		{"mixed scalars", IN("id", 1, "a", true), `WHERE "id" IN ($1,$2,$3) LIMIT 1000`, []interface{}{1, "a", true}},
+	}
+	for _, c := range cases {
+		// option may be built multiple times, producing the same query
+		for i := 0; i < 2; i++ {
+			q, err := Build([]Option{c.opt}, OpSelect)
+			if err != nil {
+				t.Fatalf("%s: cannot build query: %v", c.name, err)
+			}
+			if q.Query != c.query {
+				t.Errorf("%s: expected query (%s), got (%s)", c.name, c.query, q.Query)
+			}
+			if !reflect.DeepEqual(q.Args, c.args) {
+				t.Errorf("%s: expected args %v, got %v", c.name, c.args, q.Args)
+			}
+		}
+	}
+
+	for _, opt := range []Option{IN("id"), IN("id", []int64{})} {
+		if _, err := Build([]Option{opt}, OpSelect); err == nil {
+			t.Errorf("expected error for empty values")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package pgc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// RetryPolicy describes how operations failed with transient errors are retried.
+// It is applied to dialing and to read operations (Select, Get, Count etc.) outside of transaction.
+type RetryPolicy struct {
+	// MaxAttempts is a max number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is a delay before the first retry, every next delay is multiplied by Multiplier
+	// (2 by default), but not longer than MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is a fraction (0..1) of a delay, randomly subtracted from it, so retries of concurrent
+	// operations are spread in time.
+	Jitter float64
+
+	// TransientCodes are SQLSTATE codes (e.g. "57P03") or classes (e.g. "08") of postgres errors
+	// considered transient. Network errors are always considered transient.
+	TransientCodes []string
+}
+
+// DefaultRetryPolicy retries connection failures, server restarts and too many connections errors.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	TransientCodes: []string{
+		"08",    // connection exception
+		"57P01", // admin shutdown
+		"57P02", // crash shutdown
+		"57P03", // cannot connect now, e.g. the database system is starting up
+		"53300", // too many connections
+	},
+}
+
+// legacyDialPolicy is used for dialing in case no retry policy configured.
+var legacyDialPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 2 * time.Second, MaxBackoff: 2 * time.Second}
+
+// backoff returns delay before retry attempt (starting from 1).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff != 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// isTransient checks whether operation failed with err may succeed if retried.
+func (p *RetryPolicy) isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code := ErrorCode(err); code != "" {
+		for _, transientCode := range p.TransientCodes {
+			if strings.HasPrefix(code, transientCode) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, pgx.ErrDeadConn)
+}
+
+// do calls fn until it succeeds, fails with not retryable error, or attempts are exhausted.
+// Waiting between attempts is interrupted once ctx is done.
+func (p *RetryPolicy) do(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt < p.MaxAttempts && err != nil && retryable(err); attempt++ {
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn()
+	}
+
+	return err
+}
+
+// dialFunc returns dial func, which retries to connect on failure according to retry policy of config.
+// Connection attempt is given up after timeout, if set.
+func (c *Config) dialFunc(timeout time.Duration) pgx.DialFunc {
+	policy := &legacyDialPolicy
+	if c.RetryPolicy != nil {
+		policy = c.RetryPolicy
+	}
+	dialer := &net.Dialer{KeepAlive: 5 * time.Minute, Timeout: timeout}
+
+	return func(network, addr string) (net.Conn, error) {
+		return dial(dialer, policy, network, addr)
+	}
+}
+
+// dial connects to the address, retrying on any failure according to policy.
+func dial(dialer *net.Dialer, policy *RetryPolicy, network, addr string) (net.Conn, error) {
+	var con net.Conn
+	err := policy.do(context.Background(), func(error) bool { return true }, func() (err error) {
+		con, err = dialer.Dial(network, addr)
+		return err
+	})
+
+	return con, err
+}
+
+// retryRead performs read operation fn, retrying it according to retry policy of db config.
+// Operations within transaction are not retried.
+func (a *crudAdapter) retryRead(fn func() error) error {
+	policy := a.db.config.RetryPolicy
+	if policy == nil || a.con != connection(a.db.pool) {
+		return fn()
+	}
+
+	return policy.do(a.getContext(), policy.isTransient, fn)
+}
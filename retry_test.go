@@ -0,0 +1,111 @@
+package pgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 4 * time.Millisecond
+
+	t.Run("transient errors", func(t *testing.T) {
+		transient := []error{
+			pgx.PgError{Code: "57P03", Message: "the database system is starting up"},
+			pgx.PgError{Code: "08006"},
+			wrapError("select", pgx.PgError{Code: "53300"}),
+			io.ErrUnexpectedEOF,
+			fmt.Errorf("read: %w", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}),
+			pgx.ErrDeadConn,
+		}
+		for _, err := range transient {
+			if !policy.isTransient(err) {
+				t.Errorf("error (%v) expected to be transient", err)
+			}
+		}
+		permanent := []error{
+			pgx.PgError{Code: PGECUniqueViolation},
+			pgx.ErrNoRows,
+			context.Canceled,
+			errors.New("some error"),
+		}
+		for _, err := range permanent {
+			if policy.isTransient(err) {
+				t.Errorf("error (%v) expected not to be transient", err)
+			}
+		}
+	})
+	t.Run("backoff", func(t *testing.T) {
+		for attempt := 1; attempt < 10; attempt++ {
+			if d := policy.backoff(attempt); d <= 0 || d > policy.MaxBackoff {
+				t.Errorf("backoff of attempt (%d) expected to be within (0, %s], actual: %s", attempt, policy.MaxBackoff, d)
+			}
+		}
+	})
+	t.Run("attempts", func(t *testing.T) {
+		var calls int
+		err := policy.do(context.Background(), policy.isTransient, func() error {
+			calls++
+			return io.EOF
+		})
+		if err != io.EOF || calls != policy.MaxAttempts {
+			t.Errorf("expected (%d) attempts, actual: (%d), error: %v", policy.MaxAttempts, calls, err)
+		}
+
+		calls = 0
+		err = policy.do(context.Background(), policy.isTransient, func() error {
+			calls++
+			if calls == 1 {
+				return io.EOF
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("expected success on second attempt, actual: (%d) attempts, error: %v", calls, err)
+		}
+
+		calls = 0
+		policy.do(context.Background(), policy.isTransient, func() error {
+			calls++
+			return pgx.PgError{Code: PGECUniqueViolation}
+		})
+		if calls != 1 {
+			t.Errorf("permanent error shouldn't be retried, attempts: (%d)", calls)
+		}
+
+		calls = 0
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		policy.do(ctx, policy.isTransient, func() error {
+			calls++
+			return io.EOF
+		})
+		if calls != 1 {
+			t.Errorf("retry expected to stop once context is done, attempts: (%d)", calls)
+		}
+	})
+	t.Run("dial", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot listen: %v", err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+
+		start := time.Now()
+		if _, err := (&Config{RetryPolicy: &policy}).dialFunc(time.Second)("tcp", addr); err == nil {
+			t.Fatalf("dial error expected")
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("dial expected to use configured retry policy, took %s", time.Since(start))
+		}
+	})
+}
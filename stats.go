@@ -0,0 +1,201 @@
+package pgc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// HealthCheckTimeout is a max time of db ping performed by health handler.
+const HealthCheckTimeout = 5 * time.Second
+
+// PoolStats describes connection pool state.
+type PoolStats struct {
+	MaxConnections   int `json:"max_connections"`
+	TotalConnections int `json:"total_connections"`
+	IdleConnections  int `json:"idle_connections"`
+	InUseConnections int `json:"in_use_connections"`
+
+	// Waiters is a number of operations waiting for a free connection at the moment, see AcquireCount.
+	Waiters int64 `json:"waiters"`
+	// AcquireCount is a total number of connection acquisitions performed by pgc operations, including transactions.
+	// Raw queries of pgc.Query and health checks acquire connections within pgx pool, so they aren't counted.
+	AcquireCount int64 `json:"acquire_count"`
+	// AcquireDuration is a total time spent waiting for a free connection by counted acquisitions,
+	// including time of establishing a new connection, if pool had no idle one.
+	AcquireDuration time.Duration `json:"acquire_duration"`
+	// AvgAcquireLatency is an average time of waiting for a free connection by counted acquisitions.
+	AvgAcquireLatency time.Duration `json:"avg_acquire_latency"`
+
+	// Replicas are stats of read replica pools, if any. Stats of replica, which hasn't been reachable yet, are empty.
+	Replicas []PoolStats `json:"replicas,omitempty"`
+}
+
+// statPool wraps pgx connection pool, collecting connection acquire statistics.
+type statPool struct {
+	*pgx.ConnPool
+
+	waiters         int64
+	acquireCount    int64
+	acquireDuration int64
+
+	// lifetime is a max connection lifetime, created keeps creation time of pool connections if lifetime is set.
+	lifetime time.Duration
+	mu       sync.Mutex
+	created  map[*pgx.Conn]time.Time
+}
+
+// acquire acquires pool connection, measuring the time spent on waiting for it.
+func (p *statPool) acquire() (*pgx.Conn, error) {
+	start := time.Now()
+	atomic.AddInt64(&p.waiters, 1)
+	con, err := p.Acquire()
+	// connection idle since it exceeded max lifetime is replaced with a new one
+	for err == nil && p.expire(con) {
+		p.ConnPool.Release(con)
+		con, err = p.Acquire()
+	}
+	atomic.AddInt64(&p.waiters, -1)
+	atomic.AddInt64(&p.acquireDuration, int64(time.Since(start)))
+	atomic.AddInt64(&p.acquireCount, 1)
+
+	return con, err
+}
+
+// acquireContext acquires pool connection, giving up once ctx is done. Connection acquired after ctx is done
+// is returned to pool.
+func (p *statPool) acquireContext(ctx context.Context) (*pgx.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		con *pgx.Conn
+		err error
+	}
+	acquired := make(chan result, 1)
+	go func() {
+		con, err := p.acquire()
+		acquired <- result{con: con, err: err}
+	}()
+
+	select {
+	case res := <-acquired:
+		return res.con, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-acquired; res.err == nil {
+				p.Release(res.con)
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// acquireConn acquires dedicated connection in case con is a pool, so the acquisition is counted in pool stats.
+// Transaction connection is returned as is. Returned release func needs to be called once the connection
+// isn't used anymore, all rows must be closed by that time.
+func acquireConn(con connection) (connection, func(), error) {
+	pool, ok := con.(*statPool)
+	if !ok {
+		return con, func() {}, nil
+	}
+	pgxCon, err := pool.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pgxCon, func() { pool.Release(pgxCon) }, nil
+}
+
+// ExecEx executes sql on pool connection.
+func (p *statPool) ExecEx(ctx context.Context, sql string, options *pgx.QueryExOptions, arguments ...interface{}) (pgx.CommandTag, error) {
+	con, err := p.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer p.Release(con)
+
+	return con.ExecEx(ctx, sql, options, arguments...)
+}
+
+// CopyFrom copies rows into table using pool connection.
+func (p *statPool) CopyFrom(tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int, error) {
+	con, err := p.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer p.Release(con)
+
+	return con.CopyFrom(tableName, columnNames, rowSrc)
+}
+
+// stats returns pool stats.
+func (p *statPool) stats() PoolStats {
+	poolStat := p.Stat()
+	s := PoolStats{
+		MaxConnections:   poolStat.MaxConnections,
+		TotalConnections: poolStat.CurrentConnections,
+		IdleConnections:  poolStat.AvailableConnections,
+		InUseConnections: poolStat.CurrentConnections - poolStat.AvailableConnections,
+		Waiters:          atomic.LoadInt64(&p.waiters),
+		AcquireCount:     atomic.LoadInt64(&p.acquireCount),
+		AcquireDuration:  time.Duration(atomic.LoadInt64(&p.acquireDuration)),
+	}
+	if s.AcquireCount != 0 {
+		s.AvgAcquireLatency = s.AcquireDuration / time.Duration(s.AcquireCount)
+	}
+
+	return s
+}
+
+// Stats returns connection pool stats.
+func (db *DB) Stats() PoolStats {
+	s := db.pool.stats()
+	for _, r := range db.replicas {
+		var replicaStats PoolStats
+		if pool := r.getPool(); pool != nil {
+			replicaStats = pool.stats()
+		}
+		s.Replicas = append(s.Replicas, replicaStats)
+	}
+
+	return s
+}
+
+// Ping checks db is reachable, performing a simple query.
+func (db *DB) Ping(ctx context.Context) error {
+	var one int
+	return db.pool.QueryRowEx(ctx, "SELECT 1", nil).Scan(&one)
+}
+
+// HealthHandler returns http handler for liveness and readiness probes. Handler pings db,
+// and responds with 200 status code if db is reachable, 503 otherwise. Response body contains pool stats.
+func (db *DB) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), HealthCheckTimeout)
+		defer cancel()
+
+		resp := struct {
+			Status string    `json:"status"`
+			Error  string    `json:"error,omitempty"`
+			Stats  PoolStats `json:"stats"`
+		}{Status: "ok"}
+		status := http.StatusOK
+		if err := db.Ping(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			resp.Status = "unavailable"
+			resp.Error = err.Error()
+		}
+		resp.Stats = db.Stats()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
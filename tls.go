@@ -0,0 +1,176 @@
+package pgc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jackc/pgx"
+)
+
+// SSL modes, see https://www.postgresql.org/docs/current/libpq-ssl.html#LIBPQ-SSL-PROTECTION
+const (
+	// SSLModeDisable disables tls.
+	SSLModeDisable = "disable"
+	// SSLModeAllow tries non tls connection first, falling back to tls without verification.
+	SSLModeAllow = "allow"
+	// SSLModePrefer tries tls connection without verification first, falling back to non tls.
+	SSLModePrefer = "prefer"
+	// SSLModeRequire requires tls, server certificate is verified only in case root certificate is set.
+	SSLModeRequire = "require"
+	// SSLModeVerifyCA requires tls and verifies server certificate is signed by trusted CA.
+	SSLModeVerifyCA = "verify-ca"
+	// SSLModeVerifyFull requires tls, verifies server certificate is signed by trusted CA
+	// and server host name matches the certificate.
+	SSLModeVerifyFull = "verify-full"
+)
+
+// sslOptions describes tls settings of postgres connection.
+type sslOptions struct {
+	Mode string
+	// RootCert is a file with trusted CA certificates. ~/.postgresql/root.crt used by default if exists,
+	// otherwise system CA pool used.
+	RootCert string
+	// Cert and Key are client certificate and its private key files,
+	// ~/.postgresql/postgresql.crt and ~/.postgresql/postgresql.key used by default if exist.
+	Cert string
+	Key  string
+}
+
+// apply sets pgx config tls settings.
+func (o sslOptions) apply(pgxConfig *pgx.ConnConfig) error {
+	pgxConfig.TLSConfig = nil
+	pgxConfig.UseFallbackTLS = false
+	pgxConfig.FallbackTLSConfig = nil
+	if o.Mode == SSLModeDisable {
+		return nil
+	}
+
+	tlsConfig, err := o.tlsConfig(pgxConfig.Host)
+	if err != nil {
+		return err
+	}
+	switch o.Mode {
+	case SSLModeAllow:
+		pgxConfig.UseFallbackTLS = true
+		pgxConfig.FallbackTLSConfig = tlsConfig
+	case SSLModePrefer:
+		pgxConfig.TLSConfig = tlsConfig
+		pgxConfig.UseFallbackTLS = true
+	default:
+		pgxConfig.TLSConfig = tlsConfig
+	}
+
+	return nil
+}
+
+// tlsConfig creates tls config for connection to host according to ssl mode.
+func (o sslOptions) tlsConfig(host string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	certs, err := o.clientCertificates()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.Certificates = certs
+
+	mode := o.Mode
+	rootCert := o.RootCert
+	switch mode {
+	case SSLModeAllow, SSLModePrefer:
+		tlsConfig.InsecureSkipVerify = true
+		return tlsConfig, nil
+	case SSLModeRequire:
+		// same as libpq, require mode verifies server certificate if root certificate specified
+		if rootCert == "" {
+			tlsConfig.InsecureSkipVerify = true
+			return tlsConfig, nil
+		}
+		mode = SSLModeVerifyCA
+	case SSLModeVerifyCA, SSLModeVerifyFull:
+		if rootCert == "" {
+			if defaultRootCert := filepath.Join(homeDir(), ".postgresql", "root.crt"); PathExists(defaultRootCert) {
+				rootCert = defaultRootCert
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid sslmode (%s)", o.Mode)
+	}
+
+	var roots *x509.CertPool
+	if rootCert != "" {
+		pem, err := ioutil.ReadFile(rootCert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read root certificate: %v", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in root certificate file (%s)", rootCert)
+		}
+	}
+
+	if mode == SSLModeVerifyFull {
+		tlsConfig.RootCAs = roots
+		tlsConfig.ServerName = host
+		return tlsConfig, nil
+	}
+
+	// verify-ca: standard verification checks host name as well, so the chain is verified manually.
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyCertChain(rawCerts, roots)
+	}
+
+	return tlsConfig, nil
+}
+
+// clientCertificates loads client certificate for authentication, if any.
+func (o sslOptions) clientCertificates() ([]tls.Certificate, error) {
+	cert, key := o.Cert, o.Key
+	if cert == "" && key == "" {
+		cert = filepath.Join(homeDir(), ".postgresql", "postgresql.crt")
+		key = filepath.Join(homeDir(), ".postgresql", "postgresql.key")
+		if !PathExists(cert) || !PathExists(key) {
+			return nil, nil
+		}
+	}
+	if cert == "" || key == "" {
+		return nil, errors.New("both client certificate and key need to be specified")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client certificate: %v", err)
+	}
+
+	return []tls.Certificate{keyPair}, nil
+}
+
+// verifyCertChain verifies server certificate is signed by one of roots (system roots if nil),
+// without checking host name.
+func verifyCertChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server didn't provide a certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("cannot parse server certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+
+	return err
+}
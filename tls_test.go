@@ -0,0 +1,156 @@
+package pgc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+func TestSSLOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgc_tls")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCert, caKey := genCert(t, nil, nil, "pgc test ca")
+	serverCert, serverKey := genCert(t, caCert, caKey, "db.internal")
+	rootFile := filepath.Join(dir, "root.crt")
+	writePEM(t, rootFile, "CERTIFICATE", caCert.Raw)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}}}
+
+	// handshake connects to the test server by host, using tls config of ssl mode.
+	handshake := func(opts sslOptions, host string) error {
+		tlsConfig, err := opts.tlsConfig(host)
+		if err != nil {
+			return err
+		}
+		clientCon, serverCon := net.Pipe()
+		defer clientCon.Close()
+		go func() {
+			tls.Server(serverCon, serverTLS).Handshake()
+			serverCon.Close()
+		}()
+
+		return tls.Client(clientCon, tlsConfig).Handshake()
+	}
+
+	t.Run("verify-full", func(t *testing.T) {
+		opts := sslOptions{Mode: SSLModeVerifyFull, RootCert: rootFile}
+		if err := handshake(opts, "db.internal"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := handshake(opts, "other.internal"); err == nil {
+			t.Errorf("host name mismatch error expected")
+		}
+	})
+	t.Run("verify-ca", func(t *testing.T) {
+		opts := sslOptions{Mode: SSLModeVerifyCA, RootCert: rootFile}
+		if err := handshake(opts, "other.internal"); err != nil {
+			t.Errorf("verify-ca shouldn't check host name, error: %v", err)
+		}
+
+		otherCA, _ := genCert(t, nil, nil, "other ca")
+		otherRootFile := filepath.Join(dir, "other_root.crt")
+		writePEM(t, otherRootFile, "CERTIFICATE", otherCA.Raw)
+		if err := handshake(sslOptions{Mode: SSLModeVerifyCA, RootCert: otherRootFile}, "db.internal"); err == nil {
+			t.Errorf("unknown authority error expected")
+		}
+	})
+	t.Run("require", func(t *testing.T) {
+		if err := handshake(sslOptions{Mode: SSLModeRequire}, "other.internal"); err != nil {
+			t.Errorf("require mode shouldn't verify certificate, error: %v", err)
+		}
+	})
+	t.Run("pgx config", func(t *testing.T) {
+		var pgxConfig pgx.ConnConfig
+		if err := (sslOptions{Mode: SSLModePrefer}).apply(&pgxConfig); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pgxConfig.TLSConfig == nil || !pgxConfig.UseFallbackTLS || pgxConfig.FallbackTLSConfig != nil {
+			t.Errorf("prefer mode expected to fall back to non tls connection")
+		}
+		if err := (sslOptions{Mode: SSLModeDisable}).apply(&pgxConfig); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pgxConfig.TLSConfig != nil || pgxConfig.UseFallbackTLS {
+			t.Errorf("tls expected to be disabled")
+		}
+		if err := (sslOptions{Mode: "unknown"}).apply(&pgxConfig); err == nil {
+			t.Errorf("error expected for unknown mode")
+		}
+	})
+	t.Run("client certificate", func(t *testing.T) {
+		clientCert, clientKey := genCert(t, caCert, caKey, "bob")
+		certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+		writePEM(t, certFile, "CERTIFICATE", clientCert.Raw)
+		keyDER, err := x509.MarshalECPrivateKey(clientKey)
+		if err != nil {
+			t.Fatalf("cannot marshal key: %v", err)
+		}
+		writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+		tlsConfig, err := sslOptions{Mode: SSLModeRequire, Cert: certFile, Key: keyFile}.tlsConfig("db.internal")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(tlsConfig.Certificates) != 1 {
+			t.Errorf("client certificate expected to be loaded")
+		}
+		if _, err := (sslOptions{Mode: SSLModeRequire, Cert: certFile}).tlsConfig("db.internal"); err == nil {
+			t.Errorf("error expected for missing client key")
+		}
+	})
+}
+
+// genCert generates certificate for host signed by parent, or self signed CA certificate if parent is nil.
+func genCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, host string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{host},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("cannot parse certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+func writePEM(t *testing.T, fileName, blockType string, data []byte) {
+	if err := ioutil.WriteFile(fileName, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600); err != nil {
+		t.Fatalf("cannot write pem file: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package pgc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// DefaultTxMaxAttempts is a max number of RunInTx attempts in case TxOptions.MaxAttempts isn't set.
+const DefaultTxMaxAttempts = 3
+
+// TxOptions describes transaction settings.
+type TxOptions struct {
+	// IsoLevel is a transaction isolation level (pgx.Serializable, pgx.RepeatableRead etc.),
+	// db default isolation level used if empty.
+	IsoLevel   pgx.TxIsoLevel
+	ReadOnly   bool
+	Deferrable bool
+
+	// MaxAttempts is a max number of RunInTx attempts, including the first one,
+	// in case transaction fails with serialization failure or deadlock. DefaultTxMaxAttempts used if 0.
+	MaxAttempts int
+}
+
+// pgxOptions converts options to pgx transaction options.
+func (o *TxOptions) pgxOptions() *pgx.TxOptions {
+	if o == nil {
+		return nil
+	}
+	opts := &pgx.TxOptions{IsoLevel: o.IsoLevel}
+	if o.ReadOnly {
+		opts.AccessMode = pgx.ReadOnly
+	}
+	if o.Deferrable {
+		opts.DeferrableMode = pgx.Deferrable
+	}
+
+	return opts
+}
+
+// retryPolicy returns policy of RunInTx retries.
+func (o *TxOptions) retryPolicy() *RetryPolicy {
+	maxAttempts := DefaultTxMaxAttempts
+	if o != nil && o.MaxAttempts != 0 {
+		maxAttempts = o.MaxAttempts
+	}
+
+	return &RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.5,
+	}
+}
+
+// isTxRetryable checks whether transaction failed with err may succeed if run again.
+func isTxRetryable(err error) bool {
+	return IsSerializationFailureError(err) || IsDeadlockError(err)
+}
+
+// txContextKey is a context key of transaction bound to context.
+type txContextKey struct{}
+
+// ContextWithTx returns a copy of ctx, bound to transaction. Adapters bound to the returned context
+// with WithContext perform operations within the transaction, so services, calling
+// pgc.WithContext(ctx), may be composed within a single transaction without passing TxAdapter around.
+func ContextWithTx(ctx context.Context, tx *TxAdapter) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns transaction bound to ctx, if any.
+func TxFromContext(ctx context.Context) (*TxAdapter, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*TxAdapter)
+	return tx, ok && tx != nil
+}
+
+// joinTx makes adapter work within transaction bound to adapter context, if the transaction belongs to the same db.
+// Adapter should be a fresh copy, since it's modified in place.
+func (a *crudAdapter) joinTx() *crudAdapter {
+	tx, ok := TxFromContext(a.getContext())
+	if !ok || tx.db != a.db {
+		if a.tx != nil {
+			a.con, a.tx = a.db.pool, nil
+		}
+		return a
+	}
+	a.con, a.tx = tx.con, tx
+
+	return a
+}
+
+// BeginTx begins new transaction with options. Nil options mean db defaults.
+// In case adapter joined transaction bound to context, nested transaction is started and opts are ignored.
+func (a *Adapter) BeginTx(opts *TxOptions) (*TxAdapter, error) {
+	if a.tx != nil {
+		return a.tx.WithContext(a.getContext()).Begin()
+	}
+
+	poolCon, err := a.db.pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	con, err := poolCon.BeginEx(a.getContext(), opts.pgxOptions())
+	if err != nil {
+		a.db.pool.Release(poolCon)
+		return nil, err
+	}
+
+	txAdapter := *a.crudAdapter
+	txAdapter.con = con
+
+	return &TxAdapter{crudAdapter: &txAdapter, nested: new(int32), poolCon: poolCon}, nil
+}
+
+// RunInTx runs fn within transaction, bound to ctx. Transaction is committed in case fn returns nil,
+// and rolled back in case fn returns an error or panics. In case transaction fails with serialization failure
+// or deadlock, fn is called again in a new transaction, up to opts.MaxAttempts times,
+// so fn must not have side effects outside of transaction.
+// In case transaction is bound to ctx (see ContextWithTx), fn is run once within nested transaction.
+func (a *Adapter) RunInTx(ctx context.Context, opts *TxOptions, fn func(tx *TxAdapter) error) error {
+	a = a.WithContext(ctx)
+	if a.tx != nil {
+		// serialization failure aborts the outer transaction as well, so there is no point to retry.
+		return a.runTx(opts, fn)
+	}
+
+	return opts.retryPolicy().do(ctx, isTxRetryable, func() error {
+		return a.runTx(opts, fn)
+	})
+}
+
+// runTx runs fn within a single transaction.
+func (a *Adapter) runTx(opts *TxOptions, fn func(tx *TxAdapter) error) error {
+	tx, err := a.BeginTx(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Begin begins nested transaction, backed by a savepoint. Commit of nested transaction releases
+// the savepoint, Rollback rolls back to it, leaving outer transaction open.
+// This allows code, starting transaction with Begin, to work both standalone and within outer transaction.
+func (a *TxAdapter) Begin() (*TxAdapter, error) {
+	savepoint := fmt.Sprintf("pgc_savepoint_%d", atomic.AddInt32(a.nested, 1))
+	if err := a.Savepoint(savepoint); err != nil {
+		return nil, err
+	}
+
+	tx := *a
+	tx.savepoint = savepoint
+
+	return &tx, nil
+}
+
+// Savepoint establishes new savepoint within transaction.
+func (a *TxAdapter) Savepoint(name string) error {
+	return a.execTx("SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// RollbackTo rolls back all changes made after the savepoint was established.
+// The savepoint remains valid, so it's possible to roll back to it again.
+func (a *TxAdapter) RollbackTo(name string) error {
+	return a.execTx("ROLLBACK TO SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// Release destroys the savepoint, keeping changes made after it was established.
+func (a *TxAdapter) Release(name string) error {
+	return a.execTx("RELEASE SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// rollbackNested rolls back nested transaction and releases its savepoint.
+func (a *TxAdapter) rollbackNested() error {
+	if err := a.RollbackTo(a.savepoint); err != nil {
+		return err
+	}
+
+	return a.Release(a.savepoint)
+}
+
+// execTx executes transaction control statement.
+func (a *TxAdapter) execTx(sql string) error {
+	if a.db.config.LogQueries {
+		fmt.Println(sql)
+	}
+	if _, err := a.con.ExecEx(a.getContext(), sql, nil); err != nil {
+		return wrapError("savepoint", err)
+	}
+
+	return nil
+}
@@ -23,10 +23,6 @@ const (
 	actionRollback = "rollback"
 	actionReset    = "reset"
 
-	PGECTableDNE        = "42P01"
-	PGECTableExists     = "42P07"
-	PGECUniqueViolation = "23505"
-
 	VersionTimeFormat = "2006-01-02:15:04:05"
 
 	// DefaultVersion is used for keeping the default schema that we don't want to execute (but still can if needed)
@@ -282,7 +278,7 @@ func execInTx(version string, migrationFunc func(a *MigrationAdapter) error) err
 		return fmt.Errorf("fail start transaction: %v", err)
 	}
 
-	migrationAdapter := &MigrationAdapter{crudAdapter: &crudAdapter{con: tx.con}}
+	migrationAdapter := &MigrationAdapter{crudAdapter: tx.crudAdapter}
 	if err := migrationFunc(migrationAdapter); err != nil {
 		if err := tx.Rollback(); err != nil {
 			panic(fmt.Sprintf("migration (%s): failed to rollback transaction: %v\n", version, err))